@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/u-one/go-xeno/xeno"
+)
+
+// xeno analyze [flags] record
+func runAnalyze(args []string) int {
+	fs := flag.NewFlagSet("analyze", flag.ExitOnError)
+	conf := xeno.AnalysisConfig{}
+	fs.IntVar(&conf.Samples, "samples", 200, "Monte Carlo samples per action")
+	fs.StringVar(&conf.Rollout, "rollout", "expert", "strategy spec playing out the samples")
+	fs.IntVar(&conf.MaxDeck, "solve", 3, "solve exactly when the deck has at most this many cards (-1: never)")
+	fs.Float64Var(&conf.Blunder, "blunder", 0.1, "win probability loss regarded as a blunder")
+	fs.Int64Var(&conf.Seed, "seed", 1, "random seed")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: xeno analyze [flags] record")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer f.Close()
+	r, err := xeno.LoadRecord(f)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	a, err := xeno.Analyze(r, conf)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	a.Print(os.Stdout)
+	return 0
+}
@@ -0,0 +1,27 @@
+// xenobot is a reference bot for the XENO bot protocol.
+// It chooses a legal action at random.
+package main
+
+import (
+	"flag"
+	"log"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/u-one/go-xeno/xeno"
+)
+
+func main() {
+	name := flag.String("name", "xenobot", "bot name")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "random seed")
+	flag.Parse()
+
+	rng := rand.New(rand.NewSource(*seed))
+	err := xeno.ServeBot(*name, os.Stdin, os.Stdout, func(v xeno.PlayerView, legal []xeno.Action) xeno.Action {
+		return legal[rng.Intn(len(legal))]
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -1,28 +1,171 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"math/rand"
+	"os"
+	"os/signal"
 	"time"
 
 	"github.com/u-one/go-xeno/xeno"
 )
 
-func init() {
-	var seed int64 = time.Now().Unix()
-	fmt.Println("Seed:", seed)
-	rand.Seed(seed)
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "tournament":
+			os.Exit(runTournament(os.Args[2:]))
+		case "sprt":
+			os.Exit(runSPRT(os.Args[2:]))
+		case "tune":
+			os.Exit(runTune(os.Args[2:]))
+		case "analyze":
+			os.Exit(runAnalyze(os.Args[2:]))
+		case "puzzle":
+			os.Exit(runPuzzle(os.Args[2:]))
+		case "stats":
+			os.Exit(runStats(os.Args[2:]))
+		}
+	}
+
+	os.Exit(runGame(os.Args[1:]))
 }
 
-func main() {
+// xeno [flags] 対局する
+func runGame(args []string) int {
+	fs := flag.NewFlagSet("xeno", flag.ExitOnError)
+	hotSeat := fs.Bool("hotseat", false, "two humans share this terminal, each seeing only their own hand")
+	defaultFile, _ := xeno.DefaultProfileFile()
+	profiles := fs.String("profiles", defaultFile, "profiles file to record career stats, shown by xeno stats")
+	var profile [2]*string
+	for i := range profile {
+		profile[i] = fs.String(fmt.Sprintf("profile%d", i+1), "", fmt.Sprintf("profile name of Player%d. empty means not recorded", i+1))
+	}
+	save := fs.String("save", "", "file to save the game to when interrupted by Ctrl-C")
+	resume := fs.String("resume", "", "continue the game saved by -save. the other settings are taken from the file")
+	record := fs.String("record", "", "file to save the record to when the game ends, analyzed by xeno analyze")
+	fs.Parse(args)
+
+	var game *xeno.Game
+	if *resume != "" {
+		g, err := loadGame(*resume)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		game = g
+	} else {
+		var seed int64 = time.Now().Unix()
+		fmt.Println("Seed:", seed)
+		rand.Seed(seed)
+
+		conf := xeno.GameConfig{
+			Players: []xeno.PlayerConfig{
+				{Name: "Player1"},
+				{Name: "Player2"},
+			},
+			Profiles: *profiles,
+		}
+		for i := range conf.Players {
+			conf.Players[i].Profile = *profile[i]
+		}
+		if *hotSeat {
+			conf.HotSeat = true
+			for i := range conf.Players {
+				conf.Players[i].Manual = true
+			}
+		}
+
+		g, err := xeno.BuildGame(conf)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		game = g
+	}
+	// 再開したゲームは保存したときの記録を続ける
+	if *record != "" && game.Recording() == nil {
+		if *resume != "" {
+			fmt.Fprintln(os.Stderr, "the saved game is not recorded")
+			return 1
+		}
+		game.StartRecording()
+	}
+
+	// Ctrl-Cで判断待ちのまま止める
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	defer signal.Stop(sigs)
+	go func() {
+		select {
+		case <-sigs:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var err error
+	if *resume != "" {
+		err = game.ResumeContext(ctx)
+	} else {
+		err = game.LoopContext(ctx)
+	}
+	if err == nil {
+		if *record == "" {
+			return 0
+		}
+		if err := saveRecord(*record, game.Recording()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+	fmt.Println()
+	fmt.Println("interrupted")
+	if *save == "" {
+		return 1
+	}
+	if err := saveGame(*save, game); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("saved to %s. continue with: xeno -resume %s\n", *save, *save)
+	return 0
+}
+
+func loadGame(path string) (*xeno.Game, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return xeno.Load(f)
+}
 
-	conf := xeno.GameConfig{
-		Players: []xeno.PlayerConfig{
-			{Name: "Player1"},
-			{Name: "Player2"},
-		},
+func saveGame(path string, g *xeno.Game) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := g.Save(f); err != nil {
+		f.Close()
+		return err
 	}
+	return f.Close()
+}
 
-	game := xeno.NewGame(conf)
-	game.Loop()
+func saveRecord(path string, r *xeno.Record) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := r.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
 }
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+
+	"github.com/u-one/go-xeno/xeno"
+)
+
+// xeno puzzle [flags] file
+func runPuzzle(args []string) int {
+	fs := flag.NewFlagSet("puzzle", flag.ExitOnError)
+	seed := fs.Int64("seed", 1, "random seed dealing unknown cards")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: xeno puzzle [flags] file")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	puzzles, err := xeno.LoadPuzzles(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	rng := rand.New(rand.NewSource(*seed))
+	sc := bufio.NewScanner(os.Stdin)
+	solved := 0
+	for i, p := range puzzles {
+		fmt.Printf("===== 問題 %d/%d %s =====\n", i+1, len(puzzles), p.Title)
+		g, err := p.Game(rng)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		values, err := xeno.SolvePuzzle(g, xeno.SolverConfig{})
+		if err != nil {
+			fmt.Printf("解けない問題です: %v\n", err)
+			continue
+		}
+		d := g.Pending()
+		v := g.View(g.Players[d.Seat])
+		printPuzzleView(v)
+
+		var res xeno.PuzzleResult
+		for {
+			fmt.Printf("%s の手 (例: %s, qで終了): ", v.Players[d.Seat].Name, strings.TrimPrefix(xeno.FormatMove(d, xeno.LegalActions(v)[0]), seatPrefix(d.Seat)))
+			if !sc.Scan() {
+				return 0
+			}
+			line := strings.TrimSpace(sc.Text())
+			if line == "q" {
+				fmt.Printf("%d/%d 問正解\n", solved, i)
+				return 0
+			}
+			if !strings.HasPrefix(line, "P") {
+				line = seatPrefix(d.Seat) + line
+			}
+			_, a, err := xeno.ParseMove(line)
+			if err == nil {
+				res, err = xeno.CheckAnswer(values, a)
+			}
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			break
+		}
+
+		if res.Correct {
+			solved++
+			fmt.Printf("正解! 勝率 %.1f%%\n", 100*res.Answer.Win)
+		} else {
+			fmt.Printf("不正解。勝率 %.1f%% (最善 %.1f%%)\n", 100*res.Answer.Win, 100*res.Best[0].Win)
+		}
+		for _, av := range res.Values {
+			fmt.Printf("  %-24s %5.1f%%\n", xeno.FormatMove(d, av.Action), 100*av.Win)
+		}
+	}
+	fmt.Printf("%d/%d 問正解\n", solved, len(puzzles))
+	return 0
+}
+
+func seatPrefix(seat int) string {
+	return fmt.Sprintf("P%d ", seat+1)
+}
+
+func printPuzzleView(v xeno.PlayerView) {
+	for i, p := range v.Players {
+		var flags []string
+		if p.Dropped {
+			flags = append(flags, "脱落")
+		}
+		if p.Protected {
+			flags = append(flags, "守護")
+		}
+		if p.CalledWise {
+			flags = append(flags, "賢者")
+		}
+		name := fmt.Sprintf("P%d", i+1)
+		if p.Name != name {
+			name += " " + p.Name
+		}
+		fmt.Printf("%s: 手札%d枚 捨て札%v %s\n", name, p.HandCount, p.Discarded, strings.Join(flags, " "))
+	}
+	reinc := "なし"
+	if v.Reincarnation {
+		reinc = "あり"
+	}
+	fmt.Printf("山札%d枚 転生札%s\n", v.DeckCount, reinc)
+	fmt.Printf("手札: %v\n", v.Hand)
+	for s, c := range v.Known {
+		fmt.Printf("%s の手札を知っている: [%d]\n", v.Players[s].Name, c)
+	}
+	if len(v.Candidates) > 0 {
+		fmt.Printf("%v: %v\n", v.Decision, v.Candidates)
+	}
+}
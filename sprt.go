@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/u-one/go-xeno/xeno"
+)
+
+// xeno sprt [flags]
+// 終了コード: 0 候補の方が強い、1 強いとは言えない、2 引数の誤り、3 決まらなかった
+func runSPRT(args []string) int {
+	fs := flag.NewFlagSet("sprt", flag.ExitOnError)
+	conf := xeno.SPRTConfig{}
+	fs.StringVar(&conf.Candidate, "candidate", "", "strategy spec to test")
+	fs.StringVar(&conf.Baseline, "baseline", "com", "strategy spec to compare with")
+	fs.Float64Var(&conf.Elo0, "elo0", 0, "Elo difference of H0")
+	fs.Float64Var(&conf.Elo1, "elo1", 10, "Elo difference of H1")
+	fs.Float64Var(&conf.Alpha, "alpha", 0.05, "false positive rate")
+	fs.Float64Var(&conf.Beta, "beta", 0.05, "false negative rate")
+	fs.IntVar(&conf.MaxGames, "max-games", 0, "stop after this many games (0: no limit)")
+	fs.IntVar(&conf.Parallel, "parallel", 0, "games played in parallel (0: number of CPUs)")
+	fs.Int64Var(&conf.Seed, "seed", 1, "random seed")
+	fs.Parse(args)
+	if conf.Candidate == "" || conf.Elo1 <= conf.Elo0 {
+		fs.Usage()
+		return 2
+	}
+
+	status, err := xeno.RunSPRT(conf, func(s xeno.SPRTStatus) {
+		fmt.Fprintf(os.Stderr, "\r%v", s)
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Println(status)
+
+	switch status.Result {
+	case xeno.SPRTAccepted:
+		return 0
+	case xeno.SPRTRejected:
+		return 1
+	}
+	return 3
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/u-one/go-xeno/xeno"
+)
+
+// xeno stats [flags] [player]
+func runStats(args []string) int {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	defaultFile, _ := xeno.DefaultProfileFile()
+	file := fs.String("file", defaultFile, "profiles file")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: xeno stats [flags] [player]")
+		fmt.Fprintln(fs.Output(), "without player, all players are listed")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() > 1 || *file == "" {
+		fs.Usage()
+		return 2
+	}
+
+	ps, err := xeno.LoadProfileFile(*file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if fs.NArg() == 1 {
+		p, ok := ps.Players[fs.Arg(0)]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "no profile: %s\n", fs.Arg(0))
+			return 1
+		}
+		p.Print(os.Stdout)
+		return 0
+	}
+
+	var keys []string
+	for k := range ps.Players {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		p := ps.Players[k]
+		fmt.Printf("%-24s 対局%5d 勝率 %5.1f%%\n", k, p.Games, 100*p.WinRate())
+	}
+	return 0
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/u-one/go-xeno/xeno"
+)
+
+// xeno tournament [flags] spec...
+func runTournament(args []string) int {
+	fs := flag.NewFlagSet("tournament", flag.ExitOnError)
+	players := fs.String("players", "2", "comma separated numbers of players in a game")
+	swiss := fs.Bool("swiss", false, "pair by rating instead of round-robin")
+	rounds := fs.Int("rounds", 1, "rounds (repetitions of round-robin)")
+	games := fs.Int("games", 1, "games per seating")
+	seed := fs.Int64("seed", 1, "random seed")
+	board := fs.String("board", "", "leaderboard file to update")
+	profiles := fs.String("profiles", "", "profiles file to record each strategy's career stats")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: xeno tournament [flags] spec spec...")
+		fs.PrintDefaults()
+		fmt.Fprintln(fs.Output(), "strategies:", strings.Join(xeno.Strategies(), ", "))
+	}
+	fs.Parse(args)
+
+	conf := xeno.TournamentConfig{
+		Specs:  fs.Args(),
+		Swiss:  *swiss,
+		Rounds: *rounds,
+		Games:  *games,
+		Seed:   *seed,
+	}
+	for _, p := range strings.Split(*players, ",") {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid -players:", err)
+			return 2
+		}
+		conf.Players = append(conf.Players, n)
+	}
+	t, err := xeno.NewTournament(conf)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	lb := xeno.NewLeaderboard()
+	if *board != "" {
+		if f, err := os.Open(*board); err == nil {
+			lb, err = xeno.LoadLeaderboard(f)
+			f.Close()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+		}
+	}
+
+	if *profiles != "" {
+		if t.Profiles, err = xeno.LoadProfileFile(*profiles); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	t.Progress = func(game, total int) {
+		fmt.Fprintf(os.Stderr, "\r%d/%d", game, total)
+	}
+	t.Run(lb)
+	fmt.Fprintln(os.Stderr)
+	lb.Print(os.Stdout)
+
+	if *profiles != "" {
+		if err := t.Profiles.SaveFile(*profiles); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	if *board != "" {
+		f, err := os.Create(*board)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		if err := lb.Save(f); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	return 0
+}
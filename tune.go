@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/u-one/go-xeno/xeno"
+)
+
+// xeno tune [flags] [spec...]
+// 一番良かった引数を -o に書く。GameConfig.ParamFile で読める
+func runTune(args []string) int {
+	fs := flag.NewFlagSet("tune", flag.ExitOnError)
+	conf := xeno.TuneConfig{}
+	output := fs.String("o", "params.json", "output file")
+	fs.StringVar(&conf.Strategy, "strategy", "expert", "strategy to tune")
+	fs.IntVar(&conf.Population, "population", 16, "population size")
+	fs.IntVar(&conf.Generations, "generations", 10, "number of generations")
+	fs.IntVar(&conf.Games, "games", 20, "games per parameter set")
+	fs.Float64Var(&conf.Mutation, "mutation", 0.2, "mutation scale")
+	fs.IntVar(&conf.Elite, "elite", 2, "parameter sets kept as is")
+	fs.IntVar(&conf.Parallel, "parallel", 0, "parameter sets evaluated in parallel (0: number of CPUs)")
+	fs.Int64Var(&conf.Seed, "seed", 1, "random seed")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: xeno tune [flags] [spec...]")
+		fmt.Fprintln(fs.Output(), "specs are the opponents (default: com)")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	conf.Pool = fs.Args()
+
+	best, err := xeno.Tune(conf, func(f *xeno.ParamFile) {
+		fmt.Fprintf(os.Stderr, "generation %d: fitness %.3f\n", f.Generation, f.Fitness)
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer f.Close()
+	if err := best.Save(f); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println(best.Spec())
+	return 0
+}
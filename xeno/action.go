@@ -0,0 +1,234 @@
+package xeno
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DecisionType は判断待ちの種類
+type DecisionType int
+
+const (
+	DecisionNone            DecisionType = iota
+	DecisionDiscard                      // 通常の２枚の手持ちから捨てるカードを選ぶ
+	DecisionWise                         // 賢者 3枚から1枚選ぶ
+	DecisionPublicExecution              // 公開処刑 相手の2枚から1枚選ぶ
+	DecisionPlague                       // 疫病 相手の2枚から左右どちらかを選ぶ
+)
+
+func (t DecisionType) String() string {
+	switch t {
+	case DecisionDiscard:
+		return "discard"
+	case DecisionWise:
+		return "wise"
+	case DecisionPublicExecution:
+		return "public-execution"
+	case DecisionPlague:
+		return "plague"
+	}
+	return "none"
+}
+
+// Decision represents a choice the game is waiting for
+type Decision struct {
+	Type       DecisionType
+	Seat       int   // 判断するプレイヤーの席
+	Target     int   // 公開処刑・疫病の対象の席
+	Trigger    int   // 公開処刑・疫病を発動したカード
+	Candidates []int // 賢者の候補、または公開処刑・疫病の対象の手札
+}
+
+// Action は判断1回分をintにエンコードしたもの。
+//
+//	bit 16-19: DecisionType
+//	bit  8-15: カード (疫病では左右のindex)
+//	bit  4-7 : 対象の席+1 (0は対象なし)
+//	bit  0-3 : 捜査の予想
+type Action int
+
+func NewDiscardAction(card, target, expect int) Action {
+	return Action(int(DecisionDiscard)<<16 | card<<8 | (target+1)<<4 | expect)
+}
+
+func NewWiseAction(card int) Action {
+	return Action(int(DecisionWise)<<16 | card<<8)
+}
+
+func NewPublicExecutionAction(card int) Action {
+	return Action(int(DecisionPublicExecution)<<16 | card<<8)
+}
+
+func NewPlagueAction(index int) Action {
+	return Action(int(DecisionPlague)<<16 | index<<8)
+}
+
+func (a Action) Type() DecisionType {
+	return DecisionType(int(a) >> 16 & 0xf)
+}
+
+// 捨てる/選ぶカード
+func (a Action) Card() int {
+	return int(a) >> 8 & 0xff
+}
+
+// 疫病で捨てさせる位置
+func (a Action) Index() int {
+	return a.Card()
+}
+
+// 対象の席。なければ-1
+func (a Action) Target() int {
+	return int(a)>>4&0xf - 1
+}
+
+func (a Action) Expect() int {
+	return int(a) & 0xf
+}
+
+func (a Action) String() string {
+	switch a.Type() {
+	case DecisionDiscard:
+		s := fmt.Sprintf("d%d", a.Card())
+		if a.Target() >= 0 {
+			s += fmt.Sprintf(">%d", a.Target())
+		}
+		if a.Expect() > 0 {
+			s += fmt.Sprintf("?%d", a.Expect())
+		}
+		return s
+	case DecisionWise:
+		return fmt.Sprintf("w%d", a.Card())
+	case DecisionPublicExecution:
+		return fmt.Sprintf("x%d", a.Card())
+	case DecisionPlague:
+		return fmt.Sprintf("p%d", a.Index())
+	}
+	return "-"
+}
+
+// ParseAction parses the format of Action.String
+func ParseAction(s string) (Action, error) {
+	invalid := fmt.Errorf("invalid action: %q", s)
+	if len(s) < 2 {
+		return 0, invalid
+	}
+	num := func(t string) (int, bool) {
+		n, err := strconv.Atoi(t)
+		return n, err == nil && n >= 0 && n <= 10
+	}
+	switch s[0] {
+	case 'd':
+		rest, expect, target := s[1:], 0, -1
+		if i := strings.Index(rest, "?"); i >= 0 {
+			e, ok := num(rest[i+1:])
+			if !ok {
+				return 0, invalid
+			}
+			rest, expect = rest[:i], e
+		}
+		if i := strings.Index(rest, ">"); i >= 0 {
+			t, ok := num(rest[i+1:])
+			if !ok {
+				return 0, invalid
+			}
+			rest, target = rest[:i], t
+		}
+		card, ok := num(rest)
+		if !ok {
+			return 0, invalid
+		}
+		return NewDiscardAction(card, target, expect), nil
+	case 'w', 'x', 'p':
+		n, ok := num(s[1:])
+		if !ok {
+			return 0, invalid
+		}
+		switch s[0] {
+		case 'w':
+			return NewWiseAction(n), nil
+		case 'x':
+			return NewPublicExecutionAction(n), nil
+		}
+		return NewPlagueAction(n), nil
+	}
+	return 0, invalid
+}
+
+// 対象を取るカードか
+func needsTarget(card int, boyAppeared bool) bool {
+	switch card {
+	case 1:
+		// 少年は2枚目のみ革命で対象を取る
+		return boyAppeared
+	case 2, 3, 5, 6, 8, 9:
+		return true
+	}
+	return false
+}
+
+// LegalActions は視点のプレイヤーが今取れる全ての判断を列挙する
+func LegalActions(v PlayerView) []Action {
+	var actions []Action
+	switch v.Decision {
+	case DecisionDiscard:
+		if len(v.Hand) < 2 {
+			return nil
+		}
+		for _, c := range uniqueCards(v.Hand) {
+			if c == 10 {
+				// 英雄は捨てられない
+				continue
+			}
+			if !needsTarget(c, v.BoyAppeared) {
+				actions = append(actions, NewDiscardAction(c, -1, 0))
+				continue
+			}
+			for _, t := range v.Targets() {
+				if c != 2 {
+					actions = append(actions, NewDiscardAction(c, t, 0))
+					continue
+				}
+				for e := 1; e <= 10; e++ {
+					actions = append(actions, NewDiscardAction(c, t, e))
+				}
+			}
+		}
+	case DecisionWise:
+		for _, c := range uniqueCards(v.Candidates) {
+			actions = append(actions, NewWiseAction(c))
+		}
+	case DecisionPublicExecution:
+		for _, c := range uniqueCards(v.Candidates) {
+			actions = append(actions, NewPublicExecutionAction(c))
+		}
+	case DecisionPlague:
+		actions = append(actions, NewPlagueAction(0), NewPlagueAction(1))
+	}
+	return actions
+}
+
+// IsLegal reports whether a is one of LegalActions(v)
+func IsLegal(v PlayerView, a Action) bool {
+	for _, la := range LegalActions(v) {
+		if la == a {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueCards(cards []int) []int {
+	seen := map[int]bool{}
+	var u []int
+	for _, c := range cards {
+		if !seen[c] {
+			seen[c] = true
+			u = append(u, c)
+		}
+	}
+	sort.Ints(u)
+	return u
+}
@@ -0,0 +1,166 @@
+package xeno
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAction_Encoding(t *testing.T) {
+	tests := []struct {
+		name   string
+		action Action
+		typ    DecisionType
+		card   int
+		target int
+		expect int
+		str    string
+	}{
+		{"discard", NewDiscardAction(4, -1, 0), DecisionDiscard, 4, -1, 0, "d4"},
+		{"discard with target", NewDiscardAction(5, 1, 0), DecisionDiscard, 5, 1, 0, "d5>1"},
+		{"investigation", NewDiscardAction(2, 3, 10), DecisionDiscard, 2, 3, 10, "d2>3?10"},
+		{"wise", NewWiseAction(7), DecisionWise, 7, -1, 0, "w7"},
+		{"public execution", NewPublicExecutionAction(10), DecisionPublicExecution, 10, -1, 0, "x10"},
+		{"plague", NewPlagueAction(1), DecisionPlague, 1, -1, 0, "p1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := tt.action
+			if a.Type() != tt.typ || a.Card() != tt.card || a.Target() != tt.target || a.Expect() != tt.expect {
+				t.Errorf("want: %v %d %d %d, got: %v %d %d %d", tt.typ, tt.card, tt.target, tt.expect, a.Type(), a.Card(), a.Target(), a.Expect())
+			}
+			if a.String() != tt.str {
+				t.Errorf("want: %s, got: %s", tt.str, a.String())
+			}
+			if got, err := ParseAction(tt.str); err != nil || got != a {
+				t.Errorf("want: %v, got: %v %v", a, got, err)
+			}
+		})
+	}
+
+	for _, s := range []string{"", "d", "dx", "d5>", "d2>1?11", "q1", "w"} {
+		if _, err := ParseAction(s); err == nil {
+			t.Errorf("%q: want: error", s)
+		}
+	}
+}
+
+func TestLegalActions(t *testing.T) {
+	players := []PublicPlayer{{Name: "A"}, {Name: "B"}, {Name: "C", Dropped: true}}
+
+	tests := []struct {
+		name string
+		view PlayerView
+		want []Action
+	}{
+		{
+			name: "hero can not be discarded",
+			view: PlayerView{Seat: 0, Hand: []int{10, 4}, Players: players, Decision: DecisionDiscard},
+			want: []Action{NewDiscardAction(4, -1, 0)},
+		},
+		{
+			name: "dropped player is not a target",
+			view: PlayerView{Seat: 0, Hand: []int{6, 7}, Players: players, Decision: DecisionDiscard},
+			want: []Action{NewDiscardAction(6, 1, 0), NewDiscardAction(7, -1, 0)},
+		},
+		{
+			name: "first boy has no target",
+			view: PlayerView{Seat: 1, Hand: []int{1, 1}, Players: players, Decision: DecisionDiscard},
+			want: []Action{NewDiscardAction(1, -1, 0)},
+		},
+		{
+			name: "second boy",
+			view: PlayerView{Seat: 1, Hand: []int{1, 4}, Players: players, Decision: DecisionDiscard, BoyAppeared: true},
+			want: []Action{NewDiscardAction(1, 0, 0), NewDiscardAction(4, -1, 0)},
+		},
+		{
+			name: "wise",
+			view: PlayerView{Seat: 0, Hand: []int{3}, Players: players, Decision: DecisionWise, Candidates: []int{9, 2, 9}},
+			want: []Action{NewWiseAction(2), NewWiseAction(9)},
+		},
+		{
+			name: "public execution",
+			view: PlayerView{Seat: 0, Hand: []int{3}, Players: players, Decision: DecisionPublicExecution, Target: 1, Candidates: []int{10, 5}},
+			want: []Action{NewPublicExecutionAction(5), NewPublicExecutionAction(10)},
+		},
+		{
+			name: "plague",
+			view: PlayerView{Seat: 0, Hand: []int{3}, Players: players, Decision: DecisionPlague, Target: 1},
+			want: []Action{NewPlagueAction(0), NewPlagueAction(1)},
+		},
+		{
+			name: "not my decision",
+			view: PlayerView{Seat: 0, Hand: []int{3, 4}, Players: players},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := LegalActions(tt.view)
+			if !reflect.DeepEqual(tt.want, got) {
+				t.Errorf("want: %v, got: %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLegalActions_Investigation(t *testing.T) {
+	v := PlayerView{
+		Seat:     0,
+		Hand:     []int{2, 3},
+		Players:  []PublicPlayer{{Name: "A"}, {Name: "B"}, {Name: "C"}},
+		Decision: DecisionDiscard,
+	}
+
+	actions := LegalActions(v)
+	// 捜査: 2人 x 10通り, 透視: 2人
+	if len(actions) != 22 {
+		t.Errorf("want: 22, got: %d", len(actions))
+	}
+	if !IsLegal(v, NewDiscardAction(2, 2, 10)) {
+		t.Errorf("d2>2?10 should be legal")
+	}
+	if IsLegal(v, NewDiscardAction(2, 0, 1)) {
+		t.Errorf("d2>0?1 should not be legal")
+	}
+}
+
+func TestGame_View(t *testing.T) {
+	playerH := &Player{id: 0, name: "Hikaru", hand: Hand{cards: []int{7}}}
+	playerN := &Player{id: 1, name: "Nakata", hand: Hand{cards: []int{8, 4}}, discarded: []int{5}}
+
+	g := Game{
+		Deck:        &Deck{cards: []int{5, 4}, reincCard: 1},
+		Players:     []*Player{playerH, playerN},
+		boyAppeared: true,
+		turn:        3,
+		pending:     Decision{Type: DecisionPlague, Seat: 0, Target: 1, Trigger: 5, Candidates: []int{8, 4}},
+	}
+
+	got := g.View(playerH)
+	want := PlayerView{
+		Seat:  0,
+		Turn:  3,
+		Hand:  []int{7},
+		Known: map[int]int{},
+		Players: []PublicPlayer{
+			{Name: "Hikaru", HandCount: 1, Discarded: []int{}},
+			{Name: "Nakata", HandCount: 2, Discarded: []int{5}},
+		},
+		DeckCount:     2,
+		Reincarnation: true,
+		BoyAppeared:   true,
+		Decision:      DecisionPlague,
+		Target:        1,
+		Trigger:       5,
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %+v, got: %+v", want, got)
+	}
+
+	// 判断しないプレイヤーには判断待ちは見えない
+	if v := g.View(playerN); v.Decision != DecisionNone || v.Candidates != nil {
+		t.Errorf("want: no decision, got: %+v", v)
+	}
+}
@@ -0,0 +1,230 @@
+package xeno
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// InvestigationOdds は捜査の予想が当たる確率
+type InvestigationOdds struct {
+	Target int
+	Expect int
+	Hit    float64
+}
+
+// ConfrontationOdds は対決の結果の確率
+type ConfrontationOdds struct {
+	Target int
+	Win    float64
+	Draw   float64 // 引き分けは両方脱落
+	Lose   float64
+}
+
+// Advice は判断する人間への助言
+type Advice struct {
+	Hands          map[int]Distribution // 相手の席→手札の確率分布
+	Investigations []InvestigationOdds  // 当たりやすい順
+	Confrontations []ConfrontationOdds
+	Suggestion     Action // 判断がなければ0
+	Reason         string
+}
+
+// Advisor は人間の席が正当に知っていること (PlayerView と公開された Event) だけから助言する
+type Advisor struct {
+	seat   int // -1なら最初の判断で決める
+	belief *Belief
+	expert *ExpertStrategy // 提案する手の評価に使う
+}
+
+// NewAdvisor returns an advisor for seat. A negative seat is set by the first view to advise.
+func NewAdvisor(seat int) *Advisor {
+	conf := ExpertPresets["normal"]
+	conf.Temperature = 0
+	return &Advisor{seat: seat, expert: NewExpertStrategy(conf)}
+}
+
+func (a *Advisor) OnEvent(g *Game, e Event) {
+	if a.seat < 0 {
+		return
+	}
+	if a.belief == nil {
+		a.belief = NewBelief(a.seat)
+	}
+	a.belief.OnEvent(g, e)
+}
+
+func (a *Advisor) clone() *Advisor {
+	c := *a
+	if a.belief != nil {
+		c.belief = a.belief.Clone()
+	}
+	return &c
+}
+
+func (a *Advisor) beliefFor(v PlayerView) *Belief {
+	if a.seat < 0 {
+		a.seat = v.Seat
+	}
+	if a.belief == nil || a.belief.Seat() != v.Seat {
+		a.belief = NewBelief(v.Seat)
+		a.belief.Update(v, Event{Seat: v.Seat})
+	}
+	return a.belief
+}
+
+// Advise returns the odds and a suggested action for the decision of v
+func (a *Advisor) Advise(v PlayerView) Advice {
+	b := a.beliefFor(v)
+	advice := Advice{Hands: map[int]Distribution{}}
+	for _, t := range v.Targets() {
+		advice.Hands[t] = b.Hand(t)
+	}
+
+	switch v.Decision {
+	case DecisionDiscard:
+		actions := LegalActions(v)
+		best, bestScore := Action(0), math.Inf(-1)
+		for _, act := range actions {
+			if s := a.expert.score(v, b, act); s > bestScore {
+				best, bestScore = act, s
+			}
+			if act.Card() == 2 && act.Target() >= 0 {
+				advice.Investigations = append(advice.Investigations, InvestigationOdds{
+					Target: act.Target(), Expect: act.Expect(), Hit: advice.Hands[act.Target()].Prob(act.Expect()),
+				})
+			}
+		}
+		for _, t := range discardTargets(actions, 6) {
+			advice.Confrontations = append(advice.Confrontations, confrontationOdds(t, anotherCard(v.Hand, 6), advice.Hands[t]))
+		}
+		sort.SliceStable(advice.Investigations, func(i, j int) bool {
+			return advice.Investigations[i].Hit > advice.Investigations[j].Hit
+		})
+		advice.Suggestion = best
+		advice.Reason = a.reason(v, advice.Hands, best)
+	case DecisionWise:
+		best, bestScore := 0, math.Inf(-1)
+		for _, c := range uniqueCards(v.Candidates) {
+			hv := v
+			hv.Decision = DecisionDiscard
+			hv.Hand = append(append([]int{}, v.Hand...), c)
+			hv.Candidates = nil
+			for _, act := range LegalActions(hv) {
+				if s := a.expert.score(hv, b, act); s > bestScore {
+					best, bestScore = c, s
+				}
+			}
+		}
+		advice.Suggestion = NewWiseAction(best)
+		advice.Reason = fmt.Sprintf("[%d]を取ると次に一番良い手が打てる", best)
+	case DecisionPublicExecution:
+		card := v.Candidates[0]
+		for _, c := range v.Candidates {
+			if c == 10 || (card != 10 && c > card) {
+				card = c
+			}
+		}
+		advice.Suggestion = NewPublicExecutionAction(card)
+		if card == 10 {
+			advice.Reason = "英雄を捨てさせる"
+		} else {
+			advice.Reason = "大きい方を捨てさせる"
+		}
+	case DecisionPlague:
+		advice.Suggestion = NewPlagueAction(0)
+		advice.Reason = "見えないのでどちらでも同じ"
+	}
+	return advice
+}
+
+func confrontationOdds(target, keep int, d Distribution) ConfrontationOdds {
+	o := ConfrontationOdds{Target: target}
+	for c := 1; c <= 10; c++ {
+		switch {
+		case c < keep:
+			o.Win += d[c]
+		case c == keep:
+			o.Draw += d[c]
+		default:
+			o.Lose += d[c]
+		}
+	}
+	return o
+}
+
+// 提案する手の短い説明
+func (a *Advisor) reason(v PlayerView, hands map[int]Distribution, act Action) string {
+	keep := anotherCard(v.Hand, act.Card())
+	var target Distribution
+	name := ""
+	if act.Target() >= 0 {
+		target = hands[act.Target()]
+		name = v.Players[act.Target()].Name
+	}
+	var r string
+	switch act.Card() {
+	case 1:
+		if v.BoyAppeared {
+			r = fmt.Sprintf("革命で%sの英雄を狙う (英雄の確率 %.0f%%)", name, 100*target.Prob(10))
+		} else {
+			r = "少年1枚目は効果がないので安全に捨てられる"
+		}
+	case 2:
+		r = fmt.Sprintf("捜査: %sが[%d]の確率 %.0f%%", name, act.Expect(), 100*target.Prob(act.Expect()))
+	case 3:
+		r = fmt.Sprintf("透視で%sの手札を知る", name)
+	case 4:
+		r = "守護で次の手番まで守られる"
+	case 5:
+		r = fmt.Sprintf("疫病で%sの手札を減らす", name)
+	case 6:
+		o := confrontationOdds(act.Target(), keep, target)
+		r = fmt.Sprintf("対決: %sに勝つ確率 %.0f%%", name, 100*o.Win)
+	case 7:
+		r = "賢者で次に3枚から選べる"
+	case 8:
+		expected := 0.0
+		for c := 1; c <= 10; c++ {
+			expected += float64(c) * target[c]
+		}
+		r = fmt.Sprintf("交換: %sの手札の期待値 %.1f", name, expected)
+	case 9:
+		r = fmt.Sprintf("公開処刑で%sの手札を捨てさせる (英雄の確率 %.0f%%)", name, 100*target.Prob(10))
+	}
+	return fmt.Sprintf("%s。[%d]を残す", r, keep)
+}
+
+// Print writes a to w using names in v
+func (a Advice) Print(w io.Writer, v PlayerView) {
+	fmt.Fprintln(w, "----- 助言 -----")
+	var seats []int
+	for s := range a.Hands {
+		seats = append(seats, s)
+	}
+	sort.Ints(seats)
+	for _, s := range seats {
+		fmt.Fprintf(w, "%sの手札:", v.Players[s].Name)
+		d := a.Hands[s]
+		for c := 1; c <= 10; c++ {
+			if d[c] > 0 {
+				fmt.Fprintf(w, " [%d]%.0f%%", c, 100*d[c])
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	for i, o := range a.Investigations {
+		if i == 3 {
+			break
+		}
+		fmt.Fprintf(w, "捜査 %sに[%d]: %.0f%%\n", v.Players[o.Target].Name, o.Expect, 100*o.Hit)
+	}
+	for _, o := range a.Confrontations {
+		fmt.Fprintf(w, "対決 %s: 勝ち%.0f%% 引き分け%.0f%% 負け%.0f%%\n", v.Players[o.Target].Name, 100*o.Win, 100*o.Draw, 100*o.Lose)
+	}
+	if a.Suggestion != 0 {
+		fmt.Fprintf(w, "おすすめ: %v %s\n", a.Suggestion, a.Reason)
+	}
+	fmt.Fprintln(w, "----------------")
+}
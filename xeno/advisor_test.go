@@ -0,0 +1,84 @@
+package xeno
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestAdvisor(t *testing.T) {
+	suggested := map[DecisionType]int{}
+	for seed := int64(1); seed <= 30; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		g := newSeededGame(3, rng)
+		advisor := NewAdvisor(0)
+		g.AddObserver(advisor)
+		g.Start()
+		for !g.Over() {
+			d := g.Pending()
+			v := g.View(g.Players[d.Seat])
+			legal := LegalActions(v)
+			if d.Seat == 0 {
+				a := advisor.Advise(v)
+				if !IsLegal(v, a.Suggestion) || a.Reason == "" {
+					t.Fatalf("seed %d: illegal suggestion %v for %v", seed, a.Suggestion, v)
+				}
+				suggested[d.Type]++
+				for s, h := range a.Hands {
+					if math.Abs(h.sum()-1) > 1e-9 {
+						t.Errorf("seed %d: distribution of seat %d does not sum to 1: %v", seed, s, h)
+					}
+					if c, ok := v.Known[s]; ok && v.Players[s].HandCount == 1 && h.Prob(c) < 1-1e-9 {
+						t.Errorf("seed %d: known card [%d] of seat %d: %v", seed, c, s, h)
+					}
+				}
+				for _, o := range a.Investigations {
+					if o.Hit != a.Hands[o.Target].Prob(o.Expect) {
+						t.Errorf("seed %d: %v", seed, o)
+					}
+				}
+				for _, o := range a.Confrontations {
+					if math.Abs(o.Win+o.Draw+o.Lose-1) > 1e-9 {
+						t.Errorf("seed %d: %v", seed, o)
+					}
+				}
+				var buf bytes.Buffer
+				a.Print(&buf, v)
+				if !strings.Contains(buf.String(), "おすすめ") {
+					t.Errorf("suggestion is not printed: %s", buf.String())
+				}
+			}
+			if err := g.Step(legal[rng.Intn(len(legal))]); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if suggested[DecisionDiscard] == 0 {
+		t.Errorf("never advised")
+	}
+}
+
+func TestConfrontationOdds(t *testing.T) {
+	var d Distribution
+	d[2], d[5], d[8] = 0.5, 0.25, 0.25
+	o := confrontationOdds(1, 5, d)
+	if o.Win != 0.5 || o.Draw != 0.25 || o.Lose != 0.25 {
+		t.Errorf("got: %v", o)
+	}
+}
+
+func TestManualStrategy_Advisor(t *testing.T) {
+	p := NewPlayer(PlayerConfig{Manual: true, Advisor: true})
+	if s, ok := p.strategy.(*ManualStrategy); !ok || s.advisor == nil {
+		t.Errorf("want: manual with advisor, got: %#v", p.strategy)
+	}
+	s, err := NewStrategy("manual:advisor")
+	if err != nil || s.(*ManualStrategy).advisor == nil {
+		t.Errorf("want: manual with advisor, got: %#v %v", s, err)
+	}
+	if _, err := NewStrategy("manual:unknown"); err == nil {
+		t.Errorf("want: error")
+	}
+}
@@ -0,0 +1,200 @@
+package xeno
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+)
+
+// AnalysisConfig は Analyze の設定
+type AnalysisConfig struct {
+	Samples int          // 1つの手をモンテカルロで評価する回数。0なら200
+	Rollout string       // モンテカルロで残りを打つ戦略の指定。空なら"expert"
+	MaxDeck int          // 山札がこれ以下ならSolverで読み切る。0なら3、負なら使わない
+	Solver  SolverConfig // 読み切りの上限
+	Blunder float64      // 勝率をこれ以上落とした手を悪手とする。0なら0.1
+	Seed    int64
+}
+
+func (c *AnalysisConfig) defaults() {
+	if c.Samples <= 0 {
+		c.Samples = 200
+	}
+	if c.Rollout == "" {
+		c.Rollout = "expert"
+	}
+	if c.MaxDeck == 0 {
+		c.MaxDeck = 3
+	}
+	if c.Blunder <= 0 {
+		c.Blunder = 0.1
+	}
+}
+
+// MoveAnalysis は1つの判断の評価。勝率は判断した席から見えることだけで求めたもの
+type MoveAnalysis struct {
+	Index    int // 記録の何手目か
+	Move     Move
+	Turn     int
+	Decision DecisionType
+	Values   []ActionValue // 全ての合法手の勝率。良い順
+	Chosen   float64       // 選んだ手の勝率
+	Loss     float64       // 最善手との勝率の差
+	Blunder  bool
+	Solved   bool // Solverで読み切った
+}
+
+// Best returns the best action and its win probability
+func (m MoveAnalysis) Best() ActionValue {
+	return m.Values[0]
+}
+
+// PlayerSummary は1人の判断のまとめ
+type PlayerSummary struct {
+	Name     string
+	Moves    int
+	Loss     float64 // 勝率の損失の合計
+	Blunders int
+}
+
+// Analysis は記録した1ゲームの評価
+type Analysis struct {
+	Players []PlayerSummary
+	Moves   []MoveAnalysis // 合法手が1つだけの判断は含まない
+}
+
+// Analyze replays r and evaluates every decision which has more than one legal action
+func Analyze(r *Record, conf AnalysisConfig) (*Analysis, error) {
+	conf.defaults()
+	if err := checkStrategy(conf.Rollout); err != nil {
+		return nil, err
+	}
+	rng := rand.New(rand.NewSource(conf.Seed))
+	solver := NewSolver(conf.Solver)
+
+	a := &Analysis{}
+	for _, name := range r.Players {
+		a.Players = append(a.Players, PlayerSummary{Name: name})
+	}
+	i := 0
+	var merr error
+	_, err := r.Replay(func(g *Game, m Move) {
+		defer func() { i++ }()
+		if merr != nil {
+			return
+		}
+		ma, ok, err := analyzeMove(g, m.Action, conf, solver, rng)
+		if err != nil {
+			merr = fmt.Errorf("move %d: %v", i, err)
+			return
+		}
+		if !ok {
+			return
+		}
+		ma.Index, ma.Move = i, m
+
+		p := &a.Players[m.Seat]
+		p.Moves++
+		p.Loss += ma.Loss
+		if ma.Blunder {
+			p.Blunders++
+		}
+		a.Moves = append(a.Moves, ma)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if merr != nil {
+		return nil, merr
+	}
+	return a, nil
+}
+
+// 判断待ちのgで、判断する席から見て全ての合法手と選んだ手chosenを評価する。合法手が1つならfalse
+func analyzeMove(g *Game, chosen Action, conf AnalysisConfig, solver *Solver, rng *rand.Rand) (MoveAnalysis, bool, error) {
+	v := g.View(g.Players[g.Pending().Seat])
+	if len(LegalActions(v)) < 2 {
+		return MoveAnalysis{}, false, nil
+	}
+	ma := MoveAnalysis{Turn: g.Turn(), Decision: v.Decision}
+	if conf.MaxDeck > 0 && v.DeckCount <= conf.MaxDeck {
+		if values, err := solver.Solve(v); err == nil {
+			ma.Values, ma.Solved = values, true
+		}
+	}
+	if ma.Values == nil {
+		var err error
+		if ma.Values, err = monteCarloValues(v, conf, rng); err != nil {
+			return MoveAnalysis{}, false, err
+		}
+	}
+	for _, av := range ma.Values {
+		if av.Action == chosen {
+			ma.Chosen = av.Win
+		}
+	}
+	ma.Loss = ma.Best().Win - ma.Chosen
+	ma.Blunder = ma.Loss >= conf.Blunder
+	return ma, true, nil
+}
+
+// 見えないカードを配り直して、それぞれの手の後をRolloutの戦略で最後まで打つ。
+// 全ての手で同じ配り方を使う
+func monteCarloValues(v PlayerView, conf AnalysisConfig, rng *rand.Rand) ([]ActionValue, error) {
+	actions := LegalActions(v)
+	wins := make([]float64, len(actions))
+	for n := 0; n < conf.Samples; n++ {
+		seed := rng.Int63()
+		for i, act := range actions {
+			srng := rand.New(rand.NewSource(seed))
+			g, err := Determinize(v, srng)
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range g.Players {
+				if p.strategy, err = NewSeededStrategy(conf.Rollout, srng); err != nil {
+					closeStrategies(g)
+					return nil, err
+				}
+			}
+			g.Step(act)
+			g.Resume()
+			closeStrategies(g)
+			wins[i] += (cfrUtility(g, v.Seat) + 1) / 2
+		}
+	}
+	values := make([]ActionValue, len(actions))
+	for i, act := range actions {
+		values[i] = ActionValue{Action: act, Win: wins[i] / float64(conf.Samples)}
+	}
+	sort.SliceStable(values, func(i, j int) bool {
+		return values[i].Win > values[j].Win
+	})
+	return values, nil
+}
+
+// Print writes every decision, marking blunders with "??", and the summary of each player
+func (a *Analysis) Print(w io.Writer) {
+	for _, m := range a.Moves {
+		mark := ""
+		if m.Blunder {
+			mark = "??"
+		}
+		solved := ""
+		if m.Solved {
+			solved = " (読み切り)"
+		}
+		fmt.Fprintf(w, "%3d ターン%-3d %-12s %-8v%-2s 勝率 %5.1f%%  最善 %-8v %5.1f%%  損失 %5.1f%%%s\n",
+			m.Index, m.Turn, a.Players[m.Move.Seat].Name, m.Move.Action, mark,
+			100*m.Chosen, m.Best().Action, 100*m.Best().Win, 100*m.Loss, solved)
+	}
+	fmt.Fprintln(w)
+	for _, p := range a.Players {
+		avg := 0.0
+		if p.Moves > 0 {
+			avg = p.Loss / float64(p.Moves)
+		}
+		fmt.Fprintf(w, "%s: 判断%d 平均損失 %.1f%% 悪手%d\n", p.Name, p.Moves, 100*avg, p.Blunders)
+	}
+}
@@ -0,0 +1,109 @@
+package xeno
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	g := newSeededGame(2, rng)
+	r := g.StartRecording()
+	g.Loop()
+
+	a, err := Analyze(r, AnalysisConfig{Samples: 10, Rollout: "random:seed=1", Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Moves) == 0 {
+		t.Fatal("no move is analyzed")
+	}
+	moves, blunders := 0, 0
+	for _, m := range a.Moves {
+		found := false
+		for _, v := range m.Values {
+			found = found || v.Action == m.Move.Action
+		}
+		if !found || m.Loss < 0 || m.Loss != m.Best().Win-m.Chosen || m.Blunder != (m.Loss >= 0.1) {
+			t.Errorf("invalid analysis: %+v", m)
+		}
+		if m.Blunder {
+			blunders++
+		}
+	}
+	for _, p := range a.Players {
+		moves += p.Moves
+		blunders -= p.Blunders
+	}
+	if moves != len(a.Moves) || blunders != 0 {
+		t.Errorf("summary does not match: %+v", a.Players)
+	}
+
+	var buf bytes.Buffer
+	a.Print(&buf)
+	if !strings.Contains(buf.String(), a.Players[0].Name) {
+		t.Errorf("summary is not printed:\n%s", buf.String())
+	}
+
+	b, err := Analyze(r, AnalysisConfig{Samples: 10, Rollout: "random:seed=1", Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range a.Moves {
+		if a.Moves[i].Chosen != b.Moves[i].Chosen {
+			t.Errorf("not deterministic at move %d", i)
+		}
+	}
+
+	if _, err := Analyze(r, AnalysisConfig{Rollout: "unknown"}); err == nil {
+		t.Error("want: error for unknown rollout")
+	}
+
+	// 確認のときだけ作れる戦略のエラーも返す
+	var built int32
+	RegisterStrategy("test-analyze-once", func(p StrategyParams, rng *rand.Rand) (PlayerStrategy, error) {
+		if atomic.AddInt32(&built, 1) > 1 {
+			return nil, errors.New("test-analyze-once: built twice")
+		}
+		return RandomStrategy{rng}, p.check()
+	})
+	if _, err := Analyze(r, AnalysisConfig{Samples: 1, Rollout: "test-analyze-once", MaxDeck: -1}); err == nil {
+		t.Error("want: error from the second build")
+	}
+}
+
+func TestAnalyzeMove(t *testing.T) {
+	// P2の8を知っている。当てれば勝ち、外せばP2が交換で英雄を取って勝つ
+	g, err := ParsePosition("deck: ?\nreincarnation: -\nP1: hand 2 10; known P2=8\nP2: hand 8", rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := AnalysisConfig{Samples: 10, Rollout: "random:seed=1"}
+	conf.defaults()
+	rng := rand.New(rand.NewSource(1))
+
+	hit := g.eventAction(CardEvent{Card: 2, Target: g.Players[1], Expect: 8})
+	miss := g.eventAction(CardEvent{Card: 2, Target: g.Players[1], Expect: 3})
+	ma, ok, err := analyzeMove(g, miss, conf, NewSolver(SolverConfig{}), rng)
+	if err != nil || !ok {
+		t.Fatal("decision is not analyzed")
+	}
+	if !ma.Solved || ma.Decision != DecisionDiscard {
+		t.Errorf("want: solved discard, got: %+v", ma)
+	}
+	if best := ma.Best(); best.Action != hit || best.Win != 1 {
+		t.Errorf("want: %v with win 1, got: %v", hit, best)
+	}
+	if ma.Chosen != 0 || ma.Loss != 1 || !ma.Blunder {
+		t.Errorf("want: a blunder losing everything, got: %+v", ma)
+	}
+
+	ma, _, _ = analyzeMove(g, hit, conf, NewSolver(SolverConfig{}), rng)
+	if ma.Loss != 0 || ma.Blunder {
+		t.Errorf("want: no loss for the best move, got: %+v", ma)
+	}
+}
@@ -0,0 +1,125 @@
+package xeno
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 監査
+//
+// 監査を有効にすると、判断を適用するたび・ターンの始めと終わりに次を確かめる。
+//   - 有効にしたときのカードが、山札・転生札・手札・捨て札・賢者の候補にちょうど揃っている
+//   - 脱落した人は手札を持たない
+//   - 生きている人は1枚持つ。ただし最初の手番の前は0枚でもよく、
+//     捨てる判断をする人と公開処刑・疫病の対象は判断の間だけ2枚持てる
+
+// AuditError は監査で見つかった不整合
+type AuditError struct {
+	Turn       int
+	Violations []string
+	State      string // 見つけたときのゲームの状態
+}
+
+func (e *AuditError) Error() string {
+	return fmt.Sprintf("audit: turn %d: %s\n%s", e.Turn, strings.Join(e.Violations, "; "), e.State)
+}
+
+// SetAudit turns the audit mode on or off.
+// The cards in the game when it is turned on are the cards to be accounted for.
+func (g *Game) SetAudit(on bool) {
+	if on {
+		g.auditCards = g.countCards()
+	} else {
+		g.auditCards = nil
+	}
+}
+
+// AuditErr returns the first violation found in the audit mode, or nil
+func (g *Game) AuditErr() error {
+	return g.auditErr
+}
+
+// Audit checks the state of g now, whether the audit mode is on or not.
+// Without the audit mode the cards must be AllCards.
+func (g *Game) Audit() error {
+	want := g.auditCards
+	if want == nil {
+		want = map[int]int{}
+		for _, c := range AllCards {
+			want[c]++
+		}
+	}
+
+	var violations []string
+	got := g.countCards()
+	for c := 1; c <= 10; c++ {
+		if got[c] != want[c] {
+			violations = append(violations, fmt.Sprintf("%d of [%d] are in the game, want %d", got[c], c, want[c]))
+		}
+	}
+
+	d := g.pending
+	for i, p := range g.Players {
+		n := p.Hand().Count()
+		if p.Dropped() {
+			if n != 0 {
+				violations = append(violations, fmt.Sprintf("dropped %s holds %d cards", p.Name(), n))
+			}
+			continue
+		}
+		min, max := 1, 1
+		if i > g.turn {
+			// 最初の手番の前
+			min = 0
+		}
+		if (d.Type == DecisionDiscard && d.Seat == i) ||
+			((d.Type == DecisionPublicExecution || d.Type == DecisionPlague) && d.Target == i) {
+			max = 2
+		}
+		if n < min || n > max {
+			violations = append(violations, fmt.Sprintf("%s holds %d cards", p.Name(), n))
+		}
+	}
+
+	if violations == nil {
+		return nil
+	}
+	return &AuditError{
+		Turn:       g.turn,
+		Violations: violations,
+		State:      fmt.Sprintf("%v判断待ち: %+v\n", g, d),
+	}
+}
+
+// 監査が有効なら確かめて、最初の不整合を覚える
+func (g *Game) audit() {
+	if g.auditCards == nil || g.auditErr != nil {
+		return
+	}
+	if err := g.Audit(); err != nil {
+		g.auditErr = err
+		g.println(err)
+	}
+}
+
+// ゲームにあるカードの枚数。賢者で山札から出した候補も数える
+func (g *Game) countCards() map[int]int {
+	count := map[int]int{}
+	add := func(cards []int) {
+		for _, c := range cards {
+			count[c]++
+		}
+	}
+	add(g.Deck.cards)
+	if g.Deck.reincCard != 0 {
+		count[g.Deck.reincCard]++
+	}
+	for _, p := range g.Players {
+		add(p.Hand().Slice())
+		add(p.discarded)
+	}
+	if g.pending.Type == DecisionWise {
+		add(g.pending.Candidates)
+	}
+	return count
+}
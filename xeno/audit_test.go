@@ -0,0 +1,75 @@
+package xeno
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestGame_Audit(t *testing.T) {
+	for players := 2; players <= 4; players++ {
+		Simulate(SimulationConfig{Games: 50, Players: players, Seed: 4, Audit: true, Strategy: func(seat int, rng *rand.Rand) PlayerStrategy {
+			if seat%2 == 0 {
+				return NewExpertStrategy(ExpertConfig{Seed: rng.Int63()})
+			}
+			return RandomStrategy{rng: rng}
+		}}, func(i int, r *Record, g *Game) {
+			if err := g.AuditErr(); err != nil {
+				t.Fatalf("%d players, game %d: %v", players, i, err)
+			}
+		})
+	}
+}
+
+func TestGame_Audit_Violation(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	g := newSeededGame(3, rng)
+	g.SetAudit(true)
+	g.Start()
+	if err := g.Audit(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 山札から1枚なくす
+	lost := g.Deck.cards[0]
+	g.Deck.cards = g.Deck.cards[1:]
+	v := g.View(g.Players[g.Pending().Seat])
+	err := g.Step(LegalActions(v)[0])
+	ae, ok := err.(*AuditError)
+	if !ok {
+		t.Fatalf("want: *AuditError, got: %v", err)
+	}
+	if !strings.Contains(ae.Violations[0], fmt.Sprintf("of [%d]", lost)) || ae.State == "" {
+		t.Errorf("got: %v", ae)
+	}
+	if g.AuditErr() != err {
+		t.Errorf("AuditErr: %v", g.AuditErr())
+	}
+	// 2つ目以降はStepから返さない
+	for !g.Over() {
+		v := g.View(g.Players[g.Pending().Seat])
+		if err := g.Step(LegalActions(v)[0]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g = newSeededGame(2, rng)
+	g.Start()
+	g.Players[1].dropped = true
+	g.Players[1].hand.Set(3)
+	err = g.Audit()
+	if err == nil || !strings.Contains(err.Error(), "dropped プレイヤー2 holds 1 cards") {
+		t.Errorf("got: %v", err)
+	}
+}
+
+func TestDeck_takeN(t *testing.T) {
+	d := &Deck{cards: []int{1, 2, 3, 4}}
+	if got := d.takeN(2); len(got) != 2 || d.count() != 2 {
+		t.Errorf("got: %v, rest: %v", got, d.cards)
+	}
+	if got := d.takeN(3); len(got) != 2 || d.count() != 0 {
+		t.Errorf("got: %v, rest: %v", got, d.cards)
+	}
+}
@@ -0,0 +1,257 @@
+package xeno
+
+// Distribution は各カード(1-10)である確率。index 0は使わない
+type Distribution [11]float64
+
+func (d Distribution) Prob(c int) float64 {
+	if c < 1 || c > 10 {
+		return 0
+	}
+	return d[c]
+}
+
+// Most returns the most probable card
+func (d Distribution) Most() (card int, p float64) {
+	for c := 1; c <= 10; c++ {
+		if d[c] > p {
+			card, p = c, d[c]
+		}
+	}
+	return
+}
+
+func (d Distribution) sum() float64 {
+	s := 0.0
+	for c := 1; c <= 10; c++ {
+		s += d[c]
+	}
+	return s
+}
+
+func (d *Distribution) normalize() bool {
+	s := d.sum()
+	if s <= 0 {
+		return false
+	}
+	for c := 1; c <= 10; c++ {
+		d[c] /= s
+	}
+	return true
+}
+
+func pointDistribution(c int) Distribution {
+	var d Distribution
+	d[c] = 1
+	return d
+}
+
+// 自分は捨てるカードを合法手から一様に選ぶと仮定したときの、
+// 手札{remain, discard}からdiscardを捨てる尤度
+func discardLikelihood(remain, discard int) float64 {
+	if remain == 10 || remain == discard {
+		return 1
+	}
+	return 0.5
+}
+
+// Belief は1人のプレイヤーから見た相手の手札と山札の確率分布。
+// 公開された出来事(Event)と視点のプレイヤーが知っていること(PlayerView)からベイズ更新する。
+// 相手は合法手から一様に捨てると仮定し、各プレイヤーの手札は独立として扱う。
+type Belief struct {
+	seat  int
+	hands []Distribution // 元から持っているカード
+	drawn []Distribution // 引いたばかりで、まだ捨てていないカード
+	view  PlayerView
+}
+
+func NewBelief(seat int) *Belief {
+	return &Belief{seat: seat}
+}
+
+func (b *Belief) Seat() int {
+	return b.seat
+}
+
+func (b *Belief) Clone() *Belief {
+	c := *b
+	c.hands = append([]Distribution{}, b.hands...)
+	c.drawn = append([]Distribution{}, b.drawn...)
+	return &c
+}
+
+// OnEvent updates b from the view of b's seat in g
+func (b *Belief) OnEvent(g *Game, e Event) {
+	b.Update(g.View(g.Players[b.seat]), e)
+}
+
+// Update updates b with e. v is the view of b's seat just after e
+func (b *Belief) Update(v PlayerView, e Event) {
+	for len(b.hands) < len(v.Players) {
+		b.hands = append(b.hands, Distribution{})
+		b.drawn = append(b.drawn, Distribution{})
+	}
+	b.view = v
+
+	if e.Seat == b.seat && e.Type != EventInvestigation && e.Type != EventConfrontation && e.Type != EventExchange {
+		b.refine()
+		return
+	}
+
+	switch e.Type {
+	case EventDraw, EventWise:
+		// 賢者の3枚から選んだカードも山札から引いたものとみなす
+		if b.hands[e.Seat].sum() == 0 {
+			b.hands[e.Seat] = b.Deck()
+		} else {
+			b.drawn[e.Seat] = b.Deck()
+		}
+	case EventDiscard:
+		b.discarded(e.Seat, e.Card, true)
+	case EventForcedDiscard:
+		b.discarded(e.Seat, e.Card, false)
+	case EventReveal:
+		b.hands[e.Seat] = pointDistribution(e.Card)
+		b.drawn[e.Seat] = Distribution{}
+	case EventInvestigation:
+		if !e.Hit && e.Target != b.seat {
+			b.hands[e.Target][e.Expect] = 0
+		}
+	case EventExchange:
+		b.hands[e.Seat], b.hands[e.Target] = b.hands[e.Target], b.hands[e.Seat]
+	case EventConfrontation:
+		if e.Winner >= 0 && e.Winner != b.seat {
+			for c := 1; c <= e.Card; c++ {
+				b.hands[e.Winner][c] = 0
+			}
+		}
+	case EventReincarnation:
+		b.hands[e.Seat] = b.Deck()
+		b.drawn[e.Seat] = Distribution{}
+	case EventDropout:
+		b.hands[e.Seat] = Distribution{}
+		b.drawn[e.Seat] = Distribution{}
+	}
+	b.refine()
+}
+
+// 2枚からdiscardを捨てたので、残りのカードの分布を求める
+func (b *Belief) discarded(seat, discard int, voluntary bool) {
+	old, drawn := b.hands[seat], b.drawn[seat]
+	var post Distribution
+	for r := 1; r <= 10; r++ {
+		w := old[r]*drawn[discard] + old[discard]*drawn[r]
+		if voluntary {
+			w *= discardLikelihood(r, discard)
+		}
+		post[r] = w
+	}
+	b.hands[seat] = post
+	b.drawn[seat] = Distribution{}
+}
+
+// 見えているカードと知っているカードで分布を絞る
+func (b *Belief) refine() {
+	v := b.view
+	unseen := b.unseen()
+	for s, pp := range v.Players {
+		if s == b.seat {
+			continue
+		}
+		if pp.Dropped || pp.HandCount == 0 {
+			b.hands[s] = Distribution{}
+			b.drawn[s] = Distribution{}
+			continue
+		}
+		if c, ok := v.Known[s]; ok {
+			b.hands[s] = pointDistribution(c)
+		}
+		for c := 1; c <= 10; c++ {
+			if unseen[c] <= 0 {
+				b.hands[s][c] = 0
+				b.drawn[s][c] = 0
+			}
+		}
+		if !b.hands[s].normalize() {
+			// 矛盾した場合は見えていないカードから引き直したとみなす
+			b.hands[s] = b.pool(unseen)
+		}
+		if b.drawn[s].sum() > 0 {
+			b.drawn[s].normalize()
+		}
+	}
+}
+
+// 視点のプレイヤーから見えていないカードの枚数
+func (b *Belief) unseen() [11]int {
+	var unseen [11]int
+	for _, c := range AllCards {
+		unseen[c]++
+	}
+	v := b.view
+	for _, c := range v.Hand {
+		unseen[c]--
+	}
+	for _, pp := range v.Players {
+		for _, c := range pp.Discarded {
+			unseen[c]--
+		}
+	}
+	if v.Decision == DecisionWise || v.Decision == DecisionPublicExecution {
+		for _, c := range v.Candidates {
+			unseen[c]--
+		}
+	}
+	return unseen
+}
+
+// 見えていないカードから相手の手札の分を引いたもの
+func (b *Belief) pool(unseen [11]int) Distribution {
+	var d Distribution
+	for c := 1; c <= 10; c++ {
+		if unseen[c] <= 0 {
+			continue
+		}
+		e := float64(unseen[c])
+		for s := range b.hands {
+			if s != b.seat {
+				e -= b.hands[s][c] + b.drawn[s][c]
+			}
+		}
+		if e < 0 {
+			e = 0
+		}
+		// 近似による取りこぼしを防ぐため、見えていないカードは0にしない
+		d[c] = e + 1e-6
+	}
+	d.normalize()
+	return d
+}
+
+// Hand returns the distribution of the card seat holds.
+// While seat holds two cards, it is the distribution of one of them chosen at random.
+func (b *Belief) Hand(seat int) Distribution {
+	if seat == b.seat {
+		var d Distribution
+		for _, c := range b.view.Hand {
+			d[c]++
+		}
+		d.normalize()
+		return d
+	}
+	if seat >= len(b.hands) {
+		return Distribution{}
+	}
+	if b.drawn[seat].sum() == 0 {
+		return b.hands[seat]
+	}
+	var d Distribution
+	for c := 1; c <= 10; c++ {
+		d[c] = (b.hands[seat][c] + b.drawn[seat][c]) / 2
+	}
+	return d
+}
+
+// Deck returns the distribution of a card drawn from the deck
+func (b *Belief) Deck() Distribution {
+	return b.pool(b.unseen())
+}
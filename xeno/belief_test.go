@@ -0,0 +1,96 @@
+package xeno
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBelief_Update(t *testing.T) {
+	players := func(counts ...int) []PublicPlayer {
+		pp := make([]PublicPlayer, len(counts))
+		for i, n := range counts {
+			pp[i] = PublicPlayer{HandCount: n, Discarded: []int{}}
+		}
+		return pp
+	}
+
+	b := NewBelief(0)
+	b.Update(PlayerView{Seat: 0, Hand: []int{9}, Players: players(1, 0)}, Event{Type: EventDraw, Seat: 0})
+	b.Update(PlayerView{Seat: 0, Hand: []int{9}, Players: players(1, 1)}, Event{Type: EventDraw, Seat: 1})
+
+	// 9は自分が持っているので相手は持っていない
+	h := b.Hand(1)
+	if h.Prob(9) != 0 {
+		t.Errorf("want: 0, got: %v", h.Prob(9))
+	}
+	if math.Abs(h.Prob(10)-1.0/17) > 1e-3 || math.Abs(h.Prob(1)-2.0/17) > 1e-3 {
+		t.Errorf("want: uniform over unseen cards, got: %v", h)
+	}
+
+	// 捜査がはずれたカードは持っていない
+	b.Update(PlayerView{Seat: 0, Hand: []int{9}, Players: players(1, 1)}, Event{Type: EventInvestigation, Seat: 0, Target: 1, Expect: 10})
+	if h := b.Hand(1); h.Prob(10) != 0 || math.Abs(h.sum()-1) > 1e-9 {
+		t.Errorf("want: no 10, got: %v", h)
+	}
+
+	// 公開されたカード
+	b.Update(PlayerView{Seat: 0, Hand: []int{9}, Players: players(1, 1)}, Event{Type: EventReveal, Seat: 1, Card: 3})
+	if h := b.Hand(1); h.Prob(3) != 1 {
+		t.Errorf("want: 3, got: %v", h)
+	}
+
+	// 交換で知ったカード
+	b.Update(PlayerView{Seat: 0, Hand: []int{3}, Known: map[int]int{1: 9}, Players: players(1, 1)}, Event{Type: EventExchange, Seat: 0, Target: 1})
+	if c, p := b.Hand(1).Most(); c != 9 || p != 1 {
+		t.Errorf("want: 9, got: %d %v", c, p)
+	}
+}
+
+func TestBelief_Confrontation(t *testing.T) {
+	b := NewBelief(0)
+	pp := []PublicPlayer{{HandCount: 1}, {HandCount: 1}, {HandCount: 0, Dropped: true, Discarded: []int{6, 4}}}
+	b.Update(PlayerView{Seat: 0, Hand: []int{1}, Players: pp}, Event{Type: EventDraw, Seat: 1})
+	b.Update(PlayerView{Seat: 0, Hand: []int{1}, Players: pp}, Event{Type: EventConfrontation, Seat: 2, Target: 1, Winner: 1, Card: 4})
+
+	h := b.Hand(1)
+	for c := 1; c <= 4; c++ {
+		if h.Prob(c) != 0 {
+			t.Errorf("winner can not have %d: %v", c, h)
+		}
+	}
+}
+
+// 実際のゲームで、相手の本当の手札の確率が0にならないこと
+func TestBelief_Sound(t *testing.T) {
+	for i := 0; i < 200; i++ {
+		g := newQuietGame(2 + i%3)
+		beliefs := make([]*Belief, len(g.Players))
+		for s := range g.Players {
+			beliefs[s] = NewBelief(s)
+			g.AddObserver(beliefs[s])
+		}
+		g.AddObserver(checker(func(g *Game, e Event) {
+			for _, b := range beliefs {
+				for s, p := range g.Players {
+					if s == b.Seat() || p.Dropped() || p.Hand().Count() != 1 {
+						continue
+					}
+					if b.Hand(s).Prob(p.Hand().Get()) == 0 {
+						t.Fatalf("seat %d believes seat %d does not have %d after %v: %v", b.Seat(), s, p.Hand().Get(), e, b.Hand(s))
+					}
+				}
+			}
+		}))
+
+		g.Start()
+		for !g.Over() {
+			randomStep(t, g)
+		}
+	}
+}
+
+type checker func(g *Game, e Event)
+
+func (c checker) OnEvent(g *Game, e Event) {
+	c(g, e)
+}
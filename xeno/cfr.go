@@ -0,0 +1,414 @@
+package xeno
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// CFRConfig は CFRTrainer の設定
+type CFRConfig struct {
+	Seed int64
+}
+
+// CFRTrainer は2人対戦のゲームをモンテカルロCFR(external sampling)で学習する。
+// 情報集合は視点のプレイヤーから見える状態(PlayerView)で区別し、
+// 対象の席は視点のプレイヤーからの相対位置で持つ。
+type CFRTrainer struct {
+	rng        *rand.Rand
+	nodes      map[string]*cfrNode
+	iterations int
+}
+
+type cfrNode struct {
+	actions     []Action // 対象は相対位置
+	regrets     []float64
+	strategySum []float64
+}
+
+func NewCFRTrainer(conf CFRConfig) *CFRTrainer {
+	return &CFRTrainer{
+		rng:   rand.New(rand.NewSource(conf.Seed)),
+		nodes: map[string]*cfrNode{},
+	}
+}
+
+// Iterations returns the number of iterations trained so far
+func (t *CFRTrainer) Iterations() int {
+	return t.iterations
+}
+
+// Train runs n iterations. Each iteration traverses a newly dealt game once for each seat.
+func (t *CFRTrainer) Train(n int) {
+	for i := 0; i < n; i++ {
+		for seat := 0; seat < 2; seat++ {
+			g := newSeededGame(2, t.rng)
+			g.Start()
+			t.traverse(g, seat)
+		}
+		t.iterations++
+	}
+}
+
+// traverserから見た期待値を返す。勝ち1、負け-1、引き分け0
+func (t *CFRTrainer) traverse(g *Game, traverser int) float64 {
+	if g.Over() {
+		return cfrUtility(g, traverser)
+	}
+
+	d := g.Pending()
+	v := g.View(g.Players[d.Seat])
+	actions := LegalActions(v)
+	if len(actions) == 1 {
+		g.Step(actions[0])
+		return t.traverse(g, traverser)
+	}
+
+	node := t.node(v, actions)
+	strategy := node.strategy()
+
+	if d.Seat != traverser {
+		for i, p := range strategy {
+			node.strategySum[i] += p
+		}
+		a := node.actions[sample(t.rng, strategy)]
+		g.Step(absoluteAction(a, d.Seat, len(g.Players)))
+		return t.traverse(g, traverser)
+	}
+
+	utils := make([]float64, len(actions))
+	value := 0.0
+	for i, a := range node.actions {
+		c := g.Clone()
+		c.Step(absoluteAction(a, d.Seat, len(g.Players)))
+		utils[i] = t.traverse(c, traverser)
+		value += strategy[i] * utils[i]
+	}
+	for i := range node.regrets {
+		node.regrets[i] += utils[i] - value
+	}
+	return value
+}
+
+func cfrUtility(g *Game, seat int) float64 {
+	winners := g.Winners()
+	if len(winners) != 1 {
+		return 0
+	}
+	if g.Seat(winners[0]) == seat {
+		return 1
+	}
+	return -1
+}
+
+func (t *CFRTrainer) node(v PlayerView, actions []Action) *cfrNode {
+	key := infoSetKey(v)
+	if n, ok := t.nodes[key]; ok {
+		return n
+	}
+	n := &cfrNode{
+		actions:     make([]Action, len(actions)),
+		regrets:     make([]float64, len(actions)),
+		strategySum: make([]float64, len(actions)),
+	}
+	for i, a := range actions {
+		n.actions[i] = relativeAction(a, v.Seat, len(v.Players))
+	}
+	t.nodes[key] = n
+	return n
+}
+
+// regret matching
+func (n *cfrNode) strategy() []float64 {
+	s := make([]float64, len(n.regrets))
+	sum := 0.0
+	for i, r := range n.regrets {
+		if r > 0 {
+			s[i] = r
+			sum += r
+		}
+	}
+	for i := range s {
+		if sum > 0 {
+			s[i] /= sum
+		} else {
+			s[i] = 1 / float64(len(s))
+		}
+	}
+	return s
+}
+
+func sample(rng *rand.Rand, probs []float64) int {
+	r := rng.Float64()
+	for i, p := range probs {
+		if r < p {
+			return i
+		}
+		r -= p
+	}
+	return len(probs) - 1
+}
+
+// 対象の席を視点のプレイヤーからの相対位置にする
+func relativeAction(a Action, seat, players int) Action {
+	if a.Type() != DecisionDiscard || a.Target() < 0 {
+		return a
+	}
+	return NewDiscardAction(a.Card(), (a.Target()-seat+players)%players, a.Expect())
+}
+
+func absoluteAction(a Action, seat, players int) Action {
+	if a.Type() != DecisionDiscard || a.Target() < 0 {
+		return a
+	}
+	return NewDiscardAction(a.Card(), (a.Target()+seat)%players, a.Expect())
+}
+
+// 情報集合のキー。席は視点のプレイヤーから順に並べる
+func infoSetKey(v PlayerView) string {
+	sorted := func(cards []int) []int {
+		c := append([]int{}, cards...)
+		sort.Ints(c)
+		return c
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d:%d:%v:%d:%v:%v", v.Decision, v.Trigger, sorted(v.Hand), v.DeckCount, v.BoyAppeared, v.Reincarnation)
+	if v.Decision == DecisionWise || v.Decision == DecisionPublicExecution {
+		fmt.Fprintf(&b, ":%v", sorted(v.Candidates))
+	}
+	n := len(v.Players)
+	for i := 0; i < n; i++ {
+		seat := (v.Seat + i) % n
+		pp := v.Players[seat]
+		fmt.Fprintf(&b, "|%v:%v:%v:%d", sorted(pp.Discarded), pp.Dropped, pp.Protected, v.Known[seat])
+	}
+	return b.String()
+}
+
+// CFRPolicyVersion is the format version of CFRPolicy files
+const CFRPolicyVersion = 1
+
+// WeightedAction is an action with its probability. Target of the action is relative to the viewer.
+type WeightedAction struct {
+	Action Action
+	Prob   float32
+}
+
+// CFRPolicy は学習した平均戦略。情報集合のキーから手の確率を引く
+type CFRPolicy struct {
+	Version    int
+	Iterations int
+	Sets       map[string][]WeightedAction
+}
+
+// Policy returns the average strategy trained so far
+func (t *CFRTrainer) Policy() *CFRPolicy {
+	p := &CFRPolicy{
+		Version:    CFRPolicyVersion,
+		Iterations: t.iterations,
+		Sets:       make(map[string][]WeightedAction, len(t.nodes)),
+	}
+	for key, n := range t.nodes {
+		sum := 0.0
+		for _, s := range n.strategySum {
+			sum += s
+		}
+		if sum == 0 {
+			// 相手として一度も選ばれていない情報集合は入れない
+			continue
+		}
+		was := make([]WeightedAction, len(n.actions))
+		for i, a := range n.actions {
+			was[i] = WeightedAction{Action: a, Prob: float32(n.strategySum[i] / sum)}
+		}
+		p.Sets[key] = was
+	}
+	return p
+}
+
+// Actions returns the actions for v with their probabilities, or false if v was not trained
+func (p *CFRPolicy) Actions(v PlayerView) ([]WeightedAction, bool) {
+	was, ok := p.Sets[infoSetKey(v)]
+	if !ok {
+		return nil, false
+	}
+	abs := make([]WeightedAction, len(was))
+	for i, wa := range was {
+		abs[i] = WeightedAction{Action: absoluteAction(wa.Action, v.Seat, len(v.Players)), Prob: wa.Prob}
+	}
+	return abs, true
+}
+
+// Save writes p as gzipped gob
+func (p *CFRPolicy) Save(w io.Writer) error {
+	zw := gzip.NewWriter(w)
+	if err := gob.NewEncoder(zw).Encode(p); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// LoadCFRPolicy reads a policy written by CFRPolicy.Save
+func LoadCFRPolicy(r io.Reader) (*CFRPolicy, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	var p CFRPolicy
+	if err := gob.NewDecoder(zr).Decode(&p); err != nil {
+		return nil, err
+	}
+	if p.Version != CFRPolicyVersion {
+		return nil, fmt.Errorf("unsupported CFR policy version: %d", p.Version)
+	}
+	return &p, nil
+}
+
+// CFRStrategy はCFRPolicyの確率に従って手を選ぶ。学習していない局面はfallbackに任せる
+type CFRStrategy struct {
+	policy   *CFRPolicy
+	rng      *rand.Rand
+	fallback PlayerStrategy
+	game     *Game // 直近に通知されたゲーム。公開処刑・疫病の判断で使う
+}
+
+func NewCFRStrategy(policy *CFRPolicy, seed int64) *CFRStrategy {
+	return &CFRStrategy{
+		policy:   policy,
+		rng:      rand.New(rand.NewSource(seed)),
+		fallback: CommStrategy{opponentInfo: map[PlayerID]int{}},
+	}
+}
+
+func (s *CFRStrategy) inner() PlayerStrategy {
+	return s.fallback
+}
+
+func (s *CFRStrategy) Clone() PlayerStrategy {
+	c := *s
+	c.rng = rand.New(rand.NewSource(s.rng.Int63()))
+	if sc, ok := s.fallback.(StrategyCloner); ok {
+		c.fallback = sc.Clone()
+	}
+	return &c
+}
+
+func (s *CFRStrategy) decide(g *Game, p *Player, d Decision) (Action, bool) {
+	v := g.view(p, d)
+	was, ok := s.policy.Actions(v)
+	if !ok {
+		return 0, false
+	}
+	probs := make([]float64, len(was))
+	for i, wa := range was {
+		probs[i] = float64(wa.Prob)
+	}
+	a := was[sample(s.rng, probs)].Action
+	if !IsLegal(v, a) {
+		return 0, false
+	}
+	return a, true
+}
+
+func (s *CFRStrategy) SelectDiscard(g *Game, p *Player) CardEvent {
+	s.game = g
+	if a, ok := s.decide(g, p, Decision{Type: DecisionDiscard, Seat: g.Seat(p)}); ok {
+		return g.actionEvent(a)
+	}
+	return s.fallback.SelectDiscard(g, p)
+}
+
+func (s *CFRStrategy) SelectFromWise(g *Game, candidates []int) int {
+	s.game = g
+	if a, ok := s.decide(g, g.Players[g.pending.Seat], g.pending); ok {
+		return a.Card()
+	}
+	return s.fallback.SelectFromWise(g, candidates)
+}
+
+func (s *CFRStrategy) SelectOnPublicExecution(player, target *Player, hand Hand) int {
+	if s.game != nil {
+		if a, ok := s.decide(s.game, player, s.game.pending); ok {
+			return a.Card()
+		}
+	}
+	return s.fallback.SelectOnPublicExecution(player, target, hand)
+}
+
+func (s *CFRStrategy) SelectOnPlague(player, target *Player, hand Hand) int {
+	if s.game != nil {
+		if a, ok := s.decide(s.game, player, s.game.pending); ok {
+			return hand.At(a.Index())
+		}
+	}
+	return s.fallback.SelectOnPlague(player, target, hand)
+}
+
+func (s *CFRStrategy) KnowByClairvoyance(g *Game, player, target *Player, c int) {
+	s.game = g
+	s.fallback.KnowByClairvoyance(g, player, target, c)
+}
+
+func (s *CFRStrategy) OnOpponentEvent(g *Game, player, opponent *Player, e CardEvent) {
+	s.game = g
+	s.fallback.OnOpponentEvent(g, player, opponent, e)
+}
+
+// CFRReport は CommStrategy と対戦した結果
+type CFRReport struct {
+	Games  int
+	Wins   int
+	Losses int
+	Draws  int
+}
+
+// WinRate returns the rate of games the policy won
+func (r CFRReport) WinRate() float64 {
+	if r.Games == 0 {
+		return 0
+	}
+	return float64(r.Wins) / float64(r.Games)
+}
+
+// PayoffVsBaseline returns the expected payoff of the policy against CommStrategy
+// (win 1, loss -1). Positive means the policy beats CommStrategy.
+// This is not exploitability, which needs a best response to the policy.
+func (r CFRReport) PayoffVsBaseline() float64 {
+	if r.Games == 0 {
+		return 0
+	}
+	return float64(r.Wins-r.Losses) / float64(r.Games)
+}
+
+func (r CFRReport) String() string {
+	return fmt.Sprintf("games:%d win:%d loss:%d draw:%d win-rate:%.3f payoff:%.3f",
+		r.Games, r.Wins, r.Losses, r.Draws, r.WinRate(), r.PayoffVsBaseline())
+}
+
+// EvaluateCFR plays games between policy and CommStrategy, alternating seats
+func EvaluateCFR(policy *CFRPolicy, games int, seed int64) CFRReport {
+	rng := rand.New(rand.NewSource(seed))
+	r := CFRReport{Games: games}
+	for i := 0; i < games; i++ {
+		g := newSeededGame(2, rng)
+		seat := i % 2
+		g.Players[seat].strategy = NewCFRStrategy(policy, rng.Int63())
+		g.Players[1-seat].strategy = NewCommStrategy(rng)
+		g.Loop()
+		switch cfrUtility(g, seat) {
+		case 1:
+			r.Wins++
+		case -1:
+			r.Losses++
+		default:
+			r.Draws++
+		}
+	}
+	return r
+}
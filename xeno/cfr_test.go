@@ -0,0 +1,77 @@
+package xeno
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestCFRTrainer_Train(t *testing.T) {
+	a := NewCFRTrainer(CFRConfig{Seed: 1})
+	a.Train(20)
+	b := NewCFRTrainer(CFRConfig{Seed: 1})
+	b.Train(20)
+
+	p := a.Policy()
+	if p.Iterations != 20 || len(p.Sets) == 0 {
+		t.Fatalf("want: trained policy, got: %d iterations %d sets", p.Iterations, len(p.Sets))
+	}
+	// 同じシードなら同じ結果になる
+	if !reflect.DeepEqual(p, b.Policy()) {
+		t.Errorf("policy should be deterministic under the same seed")
+	}
+	for key, was := range p.Sets {
+		sum := float32(0)
+		for _, wa := range was {
+			sum += wa.Prob
+		}
+		if sum < 0.999 || sum > 1.001 {
+			t.Errorf("probabilities of %s do not sum to 1: %v", key, was)
+		}
+	}
+}
+
+func TestCFRPolicy_SaveLoad(t *testing.T) {
+	tr := NewCFRTrainer(CFRConfig{Seed: 2})
+	tr.Train(5)
+	p := tr.Policy()
+
+	var buf bytes.Buffer
+	if err := p.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadCFRPolicy(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p, got) {
+		t.Errorf("loaded policy differs")
+	}
+
+	if _, err := LoadCFRPolicy(bytes.NewBufferString("broken")); err == nil {
+		t.Errorf("want: error")
+	}
+}
+
+func TestCFRStrategy(t *testing.T) {
+	tr := NewCFRTrainer(CFRConfig{Seed: 3})
+	tr.Train(50)
+	r := EvaluateCFR(tr.Policy(), 20, 3)
+	if r.Games != 20 || r.Wins+r.Losses+r.Draws != 20 {
+		t.Errorf("unexpected report: %v", r)
+	}
+	if e := r.PayoffVsBaseline(); e < -1 || e > 1 {
+		t.Errorf("payoff out of range: %v", r)
+	}
+}
+
+func TestRelativeAction(t *testing.T) {
+	a := NewDiscardAction(2, 0, 7)
+	r := relativeAction(a, 1, 2)
+	if r.Target() != 1 {
+		t.Errorf("want: 1, got: %d", r.Target())
+	}
+	if got := absoluteAction(r, 1, 2); got != a {
+		t.Errorf("want: %v, got: %v", a, got)
+	}
+}
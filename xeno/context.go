@@ -0,0 +1,168 @@
+package xeno
+
+import (
+	"context"
+	"time"
+)
+
+// ContextStrategy is implemented by strategies which stop deciding when ctx is done.
+// The game asks Decide instead of the Select methods of PlayerStrategy.
+type ContextStrategy interface {
+	Decide(ctx context.Context, g *Game, d Decision) Action
+}
+
+// FallbackPolicy は時間切れになった判断の代わりの手を決める
+type FallbackPolicy func(v PlayerView) Action
+
+// FirstLegalAction is the default fallback policy, which plays the first legal action
+func FirstLegalAction(v PlayerView) Action {
+	return LegalActions(v)[0]
+}
+
+// Timeout は時間切れになった判断
+type Timeout struct {
+	Turn     int          `json:"turn"`
+	Seat     int          `json:"seat"`
+	Decision DecisionType `json:"decision"`
+	Action   Action       `json:"action"` // 代わりに打った手
+}
+
+// SetTimeout sets the time limit of each decision of p. 0 means no limit.
+// Only a ContextStrategy, including ManualStrategy waiting for the input, is stopped by the limit;
+// other strategies are waited for.
+func (p *Player) SetTimeout(d time.Duration) {
+	p.timeout = d
+}
+
+// SetFallback sets the policy deciding instead of a strategy which timed out. nil means FirstLegalAction.
+func (g *Game) SetFallback(f FallbackPolicy) {
+	g.fallback = f
+}
+
+// Timeouts returns the decisions which timed out
+func (g *Game) Timeouts() []Timeout {
+	return append([]Timeout{}, g.timeouts...)
+}
+
+// LoopContext is Loop which stops when ctx is done and returns ctx.Err().
+// A stopped game keeps the pending decision and can be continued by ResumeContext.
+func (g *Game) LoopContext(ctx context.Context) error {
+	defer g.useContext(ctx)()
+	g.printHeader()
+
+	for {
+		if err := g.ProcessTurnContext(ctx); err != nil {
+			return err
+		}
+		if g.endTurn() {
+			break
+		}
+	}
+	g.printWinners()
+	return nil
+}
+
+// ProcessTurnContext is ProcessTurn which stops when ctx is done and returns ctx.Err()
+func (g *Game) ProcessTurnContext(ctx context.Context) error {
+	defer g.useContext(ctx)()
+	defer g.println("======================================")
+
+	g.beginTurn()
+	return g.resolve(ctx)
+}
+
+// ResumeContext is Resume which stops when ctx is done and returns ctx.Err()
+func (g *Game) ResumeContext(ctx context.Context) error {
+	defer g.useContext(ctx)()
+	if g.over {
+		return nil
+	}
+	if g.pending.Type != DecisionNone {
+		if err := g.resolve(ctx); err != nil {
+			return err
+		}
+		g.println("======================================")
+		if g.endTurn() {
+			g.printWinners()
+			return nil
+		}
+	}
+	for {
+		if err := g.ProcessTurnContext(ctx); err != nil {
+			return err
+		}
+		if g.endTurn() {
+			break
+		}
+	}
+	g.printWinners()
+	return nil
+}
+
+// ゲームを進める間ctxを覚えて、通知を読む人間の待ちも止められるようにする。元に戻す関数を返す
+func (g *Game) useContext(ctx context.Context) func() {
+	prev := g.ctx
+	g.ctx = ctx
+	return func() {
+		g.ctx = prev
+	}
+}
+
+// 判断dを戦略に聞く。時間切れなら代わりの手を返す。ctxが終わればそのエラーを返す。
+// 戦略はゲームと並行して動かさず、答えるまで待つ。制限時間で止められるのはContextStrategy
+// (入力を待つManualStrategyを含む) だけで、他の戦略には制限時間はなく、ctxが終わったかは答えた後に確かめる
+func (g *Game) askContext(ctx context.Context, d Decision) (Action, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, false, err
+	}
+	p := g.Players[d.Seat]
+	parent := ctx
+	if _, ok := p.strategy.(ContextStrategy); ok && p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+	a, ok := g.askUndoable(ctx, d)
+	if err := parent.Err(); err != nil {
+		return 0, false, err
+	}
+	if !ok || ctx.Err() == nil {
+		return a, ok, nil
+	}
+
+	// 時間切れの後の答えは捨てる
+	fallback := g.fallback
+	if fallback == nil {
+		fallback = FirstLegalAction
+	}
+	a = fallback(g.view(p, d))
+	g.timeouts = append(g.timeouts, Timeout{Turn: g.turn, Seat: d.Seat, Decision: d.Type, Action: a})
+	g.recordTimeout(g.timeouts[len(g.timeouts)-1])
+	g.printf("%s 時間切れ: %v\n", p.Name(), a)
+	return a, true, nil
+}
+
+// 戦略sに判断dを聞く。他の戦略に判断を任せる戦略からも使う
+func askStrategy(ctx context.Context, g *Game, s PlayerStrategy, d Decision) Action {
+	if cs, ok := s.(ContextStrategy); ok {
+		return cs.Decide(ctx, g, d)
+	}
+	p := g.Players[d.Seat]
+	switch d.Type {
+	case DecisionWise:
+		return NewWiseAction(s.SelectFromWise(g, d.Candidates))
+	case DecisionDiscard:
+		return g.eventAction(s.SelectDiscard(g, p))
+	case DecisionPublicExecution:
+		target := g.Players[d.Target]
+		return NewPublicExecutionAction(s.SelectOnPublicExecution(p, target, target.Hand()))
+	case DecisionPlague:
+		target := g.Players[d.Target]
+		discard := s.SelectOnPlague(p, target, target.Hand())
+		if target.Hand().At(0) == discard {
+			return NewPlagueAction(0)
+		}
+		return NewPlagueAction(1)
+	}
+	return 0
+}
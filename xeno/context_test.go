@@ -0,0 +1,210 @@
+package xeno
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// ctxが終わるまで判断しない戦略
+type blockingStrategy struct {
+	RandomStrategy
+}
+
+func (s blockingStrategy) Decide(ctx context.Context, g *Game, d Decision) Action {
+	<-ctx.Done()
+	return FirstLegalAction(g.view(g.Players[d.Seat], d))
+}
+
+func TestGame_LoopContext_Timeout(t *testing.T) {
+	g := newSeededGame(2, rand.New(rand.NewSource(1)))
+	g.Players[0].strategy = blockingStrategy{}
+	g.Players[0].SetTimeout(10 * time.Millisecond)
+	fallbacks := 0
+	g.SetFallback(func(v PlayerView) Action {
+		fallbacks++
+		return FirstLegalAction(v)
+	})
+	r := g.StartRecording()
+	if err := g.LoopContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !g.Over() {
+		t.Fatal("game is not over")
+	}
+
+	timeouts := g.Timeouts()
+	if len(timeouts) == 0 || len(timeouts) != fallbacks {
+		t.Fatalf("timeouts: %v, fallbacks: %d", timeouts, fallbacks)
+	}
+	for _, to := range timeouts {
+		if to.Seat != 0 {
+			t.Errorf("timeout of seat %d", to.Seat)
+		}
+	}
+	if len(r.Timeouts) != len(timeouts) {
+		t.Errorf("recorded timeouts: %v", r.Timeouts)
+	}
+	// 代わりの手も記録から再現できる
+	if _, err := r.Replay(nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGame_LoopContext_ContextStrategy(t *testing.T) {
+	g := newSeededGame(2, rand.New(rand.NewSource(6)))
+	g.Players[0].strategy = NewISMCTSStrategy(ISMCTSConfig{Iterations: 1 << 30, Seed: 1})
+	g.Players[0].SetTimeout(10 * time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() { done <- g.LoopContext(context.Background()) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("search did not stop")
+	}
+	if len(g.Timeouts()) == 0 {
+		t.Error("no timeout")
+	}
+}
+
+func TestGame_LoopContext_Cancel(t *testing.T) {
+	g := newSeededGame(2, rand.New(rand.NewSource(3)))
+	g.Players[1].strategy = blockingStrategy{}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if err := g.LoopContext(ctx); err != context.Canceled {
+		t.Fatalf("want: %v, got: %v", context.Canceled, err)
+	}
+	if d := g.Pending(); d.Type == DecisionNone || d.Seat != 1 {
+		t.Fatalf("pending: %v", d)
+	}
+	if len(g.Timeouts()) != 0 {
+		t.Errorf("timeouts: %v", g.Timeouts())
+	}
+
+	g.Players[1].strategy = RandomStrategy{}
+	if err := g.ResumeContext(context.Background()); err != nil || !g.Over() {
+		t.Errorf("resume: %v, over: %v", err, g.Over())
+	}
+}
+
+// ContextStrategyでない戦略は止めずに答えを待つ
+func TestGame_LoopContext_NotContextStrategy(t *testing.T) {
+	g := newSeededGame(2, rand.New(rand.NewSource(1)))
+	g.Players[0].strategy = slowStrategy{}
+	g.Players[0].SetTimeout(time.Nanosecond)
+	if err := g.LoopContext(context.Background()); err != nil || !g.Over() {
+		t.Fatalf("err: %v, over: %v", err, g.Over())
+	}
+	if len(g.Timeouts()) != 0 {
+		t.Errorf("timeouts: %v", g.Timeouts())
+	}
+}
+
+type slowStrategy struct {
+	RandomStrategy
+}
+
+func (s slowStrategy) SelectDiscard(g *Game, p *Player) CardEvent {
+	time.Sleep(time.Millisecond)
+	return s.RandomStrategy.SelectDiscard(g, p)
+}
+
+func TestGame_Clone_Timeouts(t *testing.T) {
+	g := newSeededGame(2, rand.New(rand.NewSource(1)))
+	g.timeouts = []Timeout{{Seat: 0}}
+	c := g.Clone()
+	c.timeouts[0].Seat = 1
+	c.timeouts = append(c.timeouts, Timeout{})
+	if len(g.timeouts) != 1 || g.timeouts[0].Seat != 0 {
+		t.Errorf("original is changed: %v", g.timeouts)
+	}
+}
+
+func TestNewGame_Timeout(t *testing.T) {
+	g := NewGame(GameConfig{
+		Players: []PlayerConfig{
+			{Name: "A", Strategy: RandomStrategy{}},
+			{Name: "B", Strategy: RandomStrategy{}, Timeout: time.Second},
+		},
+		DecisionTimeout: time.Minute,
+		Fallback:        FirstLegalAction,
+	})
+	if g.Players[0].timeout != time.Minute || g.Players[1].timeout != time.Second || g.fallback == nil {
+		t.Errorf("got: %v %v", g.Players[0].timeout, g.Players[1].timeout)
+	}
+}
+
+// 入力が来ない人間
+func newBlockedManualGame(seed int64) (*Game, *io.PipeWriter) {
+	g := newSeededGame(2, rand.New(rand.NewSource(seed)))
+	g.Players[0].strategy = NewManualStrategy(false)
+	r, w := io.Pipe()
+	g.SetInput(r)
+	return g, w
+}
+
+func TestGame_LoopContext_ManualTimeout(t *testing.T) {
+	g, w := newBlockedManualGame(1)
+	defer w.Close()
+	g.Players[0].SetTimeout(10 * time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() { done <- g.LoopContext(context.Background()) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("input is waited for after the timeout")
+	}
+	if !g.Over() || len(g.Timeouts()) == 0 {
+		t.Errorf("over: %v, timeouts: %v", g.Over(), g.Timeouts())
+	}
+	for _, to := range g.Timeouts() {
+		if to.Seat != 0 {
+			t.Errorf("timeout of seat %d", to.Seat)
+		}
+	}
+}
+
+func TestGame_LoopContext_ManualCancel(t *testing.T) {
+	g, w := newBlockedManualGame(3)
+	defer w.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	done := make(chan error, 1)
+	go func() { done <- g.LoopContext(ctx) }()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Fatalf("want: %v, got: %v", context.Canceled, err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("input is waited for after the cancel")
+	}
+	if g.Over() || len(g.Timeouts()) != 0 {
+		t.Errorf("over: %v, timeouts: %v", g.Over(), g.Timeouts())
+	}
+
+	// 止める前に読みかけた行も、続きを打つときに受け取る
+	go func() {
+		for i := 0; ; i++ {
+			if _, err := fmt.Fprintf(w, "%d\n", i%11); err != nil {
+				return
+			}
+		}
+	}()
+	if err := g.ResumeContext(context.Background()); err != nil || !g.Over() {
+		t.Errorf("resume: %v, over: %v", err, g.Over())
+	}
+}
@@ -0,0 +1,147 @@
+package xeno
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// DatasetSchemaVersion is the version of DecisionRow.
+// Features are Observe of the acting seat's view; change this when the layout of Observe changes.
+//
+//	1: 最初の版。Featuresは132個
+const DatasetSchemaVersion = 1
+
+// DecisionRow は学習用の1つの判断。手の対象は席番号(Game.Playersのindex)で表す
+type DecisionRow struct {
+	SchemaVersion int       `json:"schema_version"`
+	Game          int       `json:"game"`
+	Turn          int       `json:"turn"`
+	Seat          int       `json:"seat"`
+	Decision      string    `json:"decision"`
+	Features      []float64 `json:"features"`
+	Legal         []string  `json:"legal"`
+	Action        string    `json:"action"`
+	Outcome       float64   `json:"outcome"` // 判断した席の結果。勝ち1、負け-1、引き分け0
+}
+
+// DecisionRows replays r and returns one row per decision. game is written to each row.
+// Observe has room for at most 4 players, so records of more players are an error.
+func DecisionRows(game int, r *Record) ([]DecisionRow, error) {
+	if len(r.Players) > envMaxPlayers {
+		return nil, fmt.Errorf("%d players: observation supports at most %d", len(r.Players), envMaxPlayers)
+	}
+	var rows []DecisionRow
+	g, err := r.Replay(func(g *Game, m Move) {
+		v := g.View(g.Players[m.Seat])
+		row := DecisionRow{
+			SchemaVersion: DatasetSchemaVersion,
+			Game:          game,
+			Turn:          v.Turn,
+			Seat:          m.Seat,
+			Decision:      v.Decision.String(),
+			Features:      Observe(v),
+			Action:        m.Action.String(),
+		}
+		for _, a := range LegalActions(v) {
+			row.Legal = append(row.Legal, a.String())
+		}
+		rows = append(rows, row)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		rows[i].Outcome = cfrUtility(g, rows[i].Seat)
+	}
+	return rows, nil
+}
+
+// DatasetWriter writes DecisionRows
+type DatasetWriter interface {
+	Write(row DecisionRow) error
+	Flush() error
+}
+
+type jsonlDatasetWriter struct {
+	enc *json.Encoder
+}
+
+// NewJSONLDatasetWriter writes one JSON object per line
+func NewJSONLDatasetWriter(w io.Writer) DatasetWriter {
+	return jsonlDatasetWriter{enc: json.NewEncoder(w)}
+}
+
+func (w jsonlDatasetWriter) Write(row DecisionRow) error {
+	return w.enc.Encode(row)
+}
+
+func (w jsonlDatasetWriter) Flush() error {
+	return nil
+}
+
+type csvDatasetWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+// NewCSVDatasetWriter writes rows with a header. Features are written to columns f0, f1, ...
+// and legal actions are separated by spaces.
+func NewCSVDatasetWriter(w io.Writer) DatasetWriter {
+	return &csvDatasetWriter{w: csv.NewWriter(w)}
+}
+
+func (w *csvDatasetWriter) Write(row DecisionRow) error {
+	if !w.header {
+		header := []string{"schema_version", "game", "turn", "seat", "decision"}
+		for i := range row.Features {
+			header = append(header, fmt.Sprintf("f%d", i))
+		}
+		header = append(header, "legal", "action", "outcome")
+		if err := w.w.Write(header); err != nil {
+			return err
+		}
+		w.header = true
+	}
+
+	record := []string{
+		strconv.Itoa(row.SchemaVersion),
+		strconv.Itoa(row.Game),
+		strconv.Itoa(row.Turn),
+		strconv.Itoa(row.Seat),
+		row.Decision,
+	}
+	for _, f := range row.Features {
+		record = append(record, strconv.FormatFloat(f, 'g', -1, 64))
+	}
+	record = append(record,
+		strings.Join(row.Legal, " "),
+		row.Action,
+		strconv.FormatFloat(row.Outcome, 'g', -1, 64),
+	)
+	return w.w.Write(record)
+}
+
+func (w *csvDatasetWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// ExportDecisions writes the decisions of records to w. Records are numbered from 0.
+func ExportDecisions(w DatasetWriter, records ...*Record) error {
+	for i, r := range records {
+		rows, err := DecisionRows(i, r)
+		if err != nil {
+			return fmt.Errorf("record %d: %v", i, err)
+		}
+		for _, row := range rows {
+			if err := w.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	return w.Flush()
+}
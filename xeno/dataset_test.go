@@ -0,0 +1,71 @@
+package xeno
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+)
+
+func TestExportDecisions(t *testing.T) {
+	var records []*Record
+	moves := 0
+	Simulate(SimulationConfig{Games: 5, Seed: 2}, func(i int, r *Record, g *Game) {
+		records = append(records, r)
+		moves += len(r.Moves)
+	})
+
+	var jsonl bytes.Buffer
+	if err := ExportDecisions(NewJSONLDatasetWriter(&jsonl), records...); err != nil {
+		t.Fatal(err)
+	}
+	rows := 0
+	sc := bufio.NewScanner(&jsonl)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		var row DecisionRow
+		if err := json.Unmarshal(sc.Bytes(), &row); err != nil {
+			t.Fatal(err)
+		}
+		if row.SchemaVersion != DatasetSchemaVersion || len(row.Features) != EnvObservationSize {
+			t.Errorf("unexpected row: %+v", row)
+		}
+		legal := false
+		for _, a := range row.Legal {
+			legal = legal || a == row.Action
+		}
+		if !legal {
+			t.Errorf("chosen action %s is not in %v", row.Action, row.Legal)
+		}
+		rows++
+	}
+	if rows != moves {
+		t.Errorf("want: %d rows, got: %d", moves, rows)
+	}
+
+	var buf bytes.Buffer
+	if err := ExportDecisions(NewCSVDatasetWriter(&buf), records...); err != nil {
+		t.Fatal(err)
+	}
+	lines, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != moves+1 {
+		t.Errorf("want: %d lines, got: %d", moves+1, len(lines))
+	}
+	if n := len(lines[0]); n != 5+EnvObservationSize+3 {
+		t.Errorf("unexpected columns: %d", n)
+	}
+}
+
+func TestDecisionRows_TooManyPlayers(t *testing.T) {
+	var record *Record
+	Simulate(SimulationConfig{Games: 1, Players: envMaxPlayers + 1, Seed: 1}, func(i int, r *Record, g *Game) {
+		record = r
+	})
+	if _, err := DecisionRows(0, record); err == nil {
+		t.Errorf("want: error for %d players", len(record.Players))
+	}
+}
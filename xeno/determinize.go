@@ -0,0 +1,170 @@
+package xeno
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+)
+
+type rngShuffler struct {
+	rng *rand.Rand
+}
+
+func (s rngShuffler) Shuffle(cards []int) []int {
+	s.rng.Shuffle(len(cards), func(i, j int) {
+		cards[i], cards[j] = cards[j], cards[i]
+	})
+	return cards
+}
+
+// Determinize builds a Game consistent with what the viewer of v knows.
+// Opponents' hands, deck order and the reincarnation card are sampled by rng
+// from the cards the viewer has not seen.
+// The decision of v becomes the pending decision of the returned game, so
+// v should be taken at the viewer's decision or between turns.
+// Players of the returned game use RandomStrategy and the output is discarded.
+// It returns an error if v is not consistent, e.g. the cards do not add up.
+func Determinize(v PlayerView, rng *rand.Rand) (*Game, error) {
+	h, err := newHidden(v)
+	if err != nil {
+		return nil, err
+	}
+	pool := append([]int{}, h.pool...)
+	rng.Shuffle(len(pool), func(i, j int) {
+		pool[i], pool[j] = pool[j], pool[i]
+	})
+	return h.fill(pool, rng), nil
+}
+
+// hidden は視点のプレイヤーから見えないカードを配る前のゲーム
+type hidden struct {
+	v       PlayerView
+	base    *Game
+	unknown []int // 各席の見えない手札の枚数
+	pool    []int // 見えないカード(昇順)。相手の手札、山札、転生札の順に配る
+}
+
+func newHidden(v PlayerView) (*hidden, error) {
+	unseen := make(map[int]int, len(AllCards))
+	for _, c := range AllCards {
+		unseen[c]++
+	}
+	see := func(cards []int) {
+		for _, c := range cards {
+			unseen[c]--
+		}
+	}
+	see(v.Hand)
+	if v.Decision == DecisionWise || v.Decision == DecisionPublicExecution {
+		see(v.Candidates)
+	}
+
+	players := make([]*Player, len(v.Players))
+	for i, pp := range v.Players {
+		players[i] = &Player{
+			id:         PlayerID(i + 1),
+			name:       pp.Name,
+			hand:       Hand{cards: []int{}},
+			discarded:  append([]int{}, pp.Discarded...),
+			protected:  pp.Protected,
+			calledWise: pp.CalledWise,
+			dropped:    pp.Dropped,
+			strategy:   RandomStrategy{},
+		}
+		see(pp.Discarded)
+	}
+	players[v.Seat].hand.cards = append([]int{}, v.Hand...)
+
+	// 知っているカードを先に配り、残りの枚数を数える
+	unknown := make([]int, len(v.Players))
+	for i, pp := range v.Players {
+		if i == v.Seat {
+			continue
+		}
+		if v.Decision == DecisionPublicExecution && i == v.Target {
+			players[i].hand.cards = append([]int{}, v.Candidates...)
+			continue
+		}
+		unknown[i] = pp.HandCount
+		if c, ok := v.Known[i]; ok && unknown[i] > 0 && unseen[c] > 0 {
+			unseen[c]--
+			players[i].hand.cards = append(players[i].hand.cards, c)
+			players[v.Seat].know(players[i].ID(), c)
+			unknown[i]--
+		}
+	}
+
+	var pool []int
+	for c := 1; c <= 10; c++ {
+		for n := 0; n < unseen[c]; n++ {
+			pool = append(pool, c)
+		}
+	}
+
+	need := v.DeckCount
+	for _, n := range unknown {
+		need += n
+	}
+	if v.Reincarnation {
+		need++
+	}
+	if len(pool) != need {
+		return nil, fmt.Errorf("determinize: %d unseen cards for %d places", len(pool), need)
+	}
+
+	return &hidden{
+		v: v,
+		base: &Game{
+			Deck:        &Deck{},
+			Players:     players,
+			boyAppeared: v.BoyAppeared,
+			turn:        v.Turn,
+			out:         ioutil.Discard,
+		},
+		unknown: unknown,
+		pool:    pool,
+	}, nil
+}
+
+// fill deals pool in order. If rng is not nil, hands of two cards are shuffled
+// and the deck is shuffled by rng after Sage.
+func (h *hidden) fill(pool []int, rng *rand.Rand) *Game {
+	v := h.v
+	g := h.base.Clone()
+	for i, n := range h.unknown {
+		if n == 0 {
+			continue
+		}
+		p := g.Players[i]
+		p.hand.cards = append(p.hand.cards, pool[:n]...)
+		pool = pool[n:]
+		if rng != nil {
+			c := p.hand.cards
+			rng.Shuffle(len(c), func(a, b int) {
+				c[a], c[b] = c[b], c[a]
+			})
+		}
+	}
+
+	g.Deck = &Deck{
+		cards:    append([]int{}, pool[:v.DeckCount]...),
+		shuffler: RandomShuffler{},
+	}
+	if rng != nil {
+		g.Deck.shuffler = rngShuffler{rng: rng}
+	}
+	if v.Reincarnation {
+		g.Deck.reincCard = pool[v.DeckCount]
+	}
+
+	if v.Decision != DecisionNone {
+		g.pending = Decision{Type: v.Decision, Seat: v.Seat, Target: v.Target, Trigger: v.Trigger}
+		switch v.Decision {
+		case DecisionWise, DecisionPublicExecution:
+			g.pending.Candidates = append([]int{}, v.Candidates...)
+		case DecisionPlague:
+			g.pending.Candidates = append([]int{}, g.Players[v.Target].hand.cards...)
+		}
+	}
+	return g
+}
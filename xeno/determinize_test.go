@@ -0,0 +1,201 @@
+package xeno
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// ゲーム中の全カードを集める
+func collectCards(g *Game) []int {
+	cards := append([]int{}, g.Deck.cards...)
+	if g.Deck.reincCard != 0 {
+		cards = append(cards, g.Deck.reincCard)
+	}
+	for _, p := range g.Players {
+		cards = append(cards, p.Hand().Slice()...)
+		cards = append(cards, p.Discarded()...)
+	}
+	if g.pending.Type == DecisionWise {
+		cards = append(cards, g.pending.Candidates...)
+	}
+	sort.Ints(cards)
+	return cards
+}
+
+func randomStep(t *testing.T, g *Game) {
+	d := g.Pending()
+	actions := LegalActions(g.View(g.Players[d.Seat]))
+	if err := g.Step(actions[rand.Intn(len(actions))]); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newQuietGame(n int) *Game {
+	conf := GameConfig{}
+	for i := 0; i < n; i++ {
+		conf.Players = append(conf.Players, PlayerConfig{})
+	}
+	g := NewGame(conf)
+	for _, p := range g.Players {
+		p.strategy = RandomStrategy{}
+	}
+	g.SetOutput(ioutil.Discard)
+	return g
+}
+
+func TestGame_Step(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		g := newQuietGame(2 + i%3)
+		g.Start()
+		for !g.Over() {
+			randomStep(t, g)
+			if got := collectCards(g); !reflect.DeepEqual(got, AllCards) {
+				t.Fatalf("cards are not conserved: %v", got)
+			}
+		}
+		// 対決の引き分けでは勝者がいないこともある
+		if len(g.Winners()) > 1 {
+			t.Errorf("want: at most 1 winner, got: %v", g.Winners())
+		}
+	}
+}
+
+func TestGame_Step_Illegal(t *testing.T) {
+	g := newQuietGame(2)
+	g.Start()
+	before := g.Clone()
+	if err := g.Step(NewPlagueAction(0)); err == nil {
+		t.Errorf("want: error")
+	}
+	if !reflect.DeepEqual(before.Pending(), g.Pending()) {
+		t.Errorf("pending should not change, want: %v, got: %v", before.Pending(), g.Pending())
+	}
+}
+
+func TestGame_Clone(t *testing.T) {
+	var g *Game
+	// 5手以内に終わったらやり直す
+	for g == nil || g.Over() {
+		g = newQuietGame(3)
+		g.Players[0].strategy = CommStrategy{opponentInfo: map[PlayerID]int{}}
+		g.Start()
+		for i := 0; i < 5 && !g.Over(); i++ {
+			randomStep(t, g)
+		}
+	}
+
+	info := len(g.Players[0].strategy.(CommStrategy).opponentInfo)
+	c := g.Clone()
+	// 入力は空のものに替わる
+	same := *c
+	same.in = g.in
+	if !reflect.DeepEqual(g, &same) {
+		t.Fatalf("want: %v, got: %v", g, c)
+	}
+
+	deck := append([]int{}, g.Deck.cards...)
+	hand := append([]int{}, g.Players[0].Hand().Slice()...)
+	for !c.Over() {
+		randomStep(t, c)
+	}
+	c.Players[0].strategy.(CommStrategy).opponentInfo[-1] = 1
+
+	if !reflect.DeepEqual(deck, g.Deck.cards) {
+		t.Errorf("deck changed, want: %v, got: %v", deck, g.Deck.cards)
+	}
+	if !reflect.DeepEqual(hand, g.Players[0].Hand().Slice()) {
+		t.Errorf("hand changed, want: %v, got: %v", hand, g.Players[0].Hand().Slice())
+	}
+	if len(g.Players[0].strategy.(CommStrategy).opponentInfo) != info {
+		t.Errorf("strategy state is shared")
+	}
+	if g.Over() {
+		t.Errorf("original game should not be over")
+	}
+}
+
+func TestGame_Clone_Settings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xeno")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "profiles.json")
+
+	g := NewGame(GameConfig{
+		Players:  []PlayerConfig{{Name: "A", Manual: true, Profile: "a"}, {Name: "B", Manual: true, Profile: "b"}},
+		HotSeat:  true,
+		Audit:    true,
+		Profiles: file,
+	})
+	var out bytes.Buffer
+	g.SetOutput(&out)
+	input := strings.NewReader("0\n")
+	g.SetInput(input)
+	g.Start()
+	out.Reset()
+
+	c := g.Clone()
+	if c.hotSeat || c.auditCards != nil || c.profileFile != "" || c.profileKeys != nil {
+		t.Errorf("settings are copied: %v %v %q %v", c.hotSeat, c.auditCards, c.profileFile, c.profileKeys)
+	}
+	// 人間の戦略のままでも、入力を読まず何も書かずに終わる
+	c.Resume()
+	if !c.Over() {
+		t.Fatal("clone is not over")
+	}
+	if input.Len() == 0 || out.Len() != 0 {
+		t.Errorf("clone used the io of the game, unread: %d, written: %q", input.Len(), out.String())
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("clone updated profiles: %v", err)
+	}
+}
+
+func TestDeterminize(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		g := newQuietGame(2 + i%3)
+		g.Start()
+		for n := rand.Intn(12); n > 0 && !g.Over(); n-- {
+			randomStep(t, g)
+		}
+		if g.Over() {
+			continue
+		}
+		p := g.Players[g.Pending().Seat]
+		v := g.View(p)
+
+		d, err := Determinize(v, rng)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := collectCards(d); !reflect.DeepEqual(got, AllCards) {
+			t.Fatalf("cards are not conserved: %v", got)
+		}
+		dv := d.View(d.Players[v.Seat])
+		if !reflect.DeepEqual(v.Hand, dv.Hand) || !reflect.DeepEqual(v.Candidates, dv.Candidates) || v.DeckCount != dv.DeckCount {
+			t.Fatalf("view changed, want: %+v, got: %+v", v, dv)
+		}
+		for seat, c := range v.Known {
+			if !d.Players[seat].Hand().Has(c) {
+				t.Errorf("known card %d is not in %v", c, d.Players[seat].Hand())
+			}
+		}
+		for seat, pp := range v.Players {
+			if d.Players[seat].Hand().Count() != pp.HandCount {
+				t.Errorf("hand count, want: %d, got: %d", pp.HandCount, d.Players[seat].Hand().Count())
+			}
+		}
+		for !d.Over() {
+			randomStep(t, d)
+		}
+	}
+}
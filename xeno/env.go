@@ -0,0 +1,243 @@
+package xeno
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+)
+
+// 観測ベクトルで扱う最大の人数
+const envMaxPlayers = 4
+
+// 観測ベクトルの各席の部分の長さ
+const envSeatSize = 5 + 10 + 10
+
+// EnvObservationSize is the length of observation vectors of Env
+const EnvObservationSize = 10 + 4 + 10 + 4 + envMaxPlayers + envMaxPlayers*envSeatSize
+
+var ErrIllegalAction = errors.New("illegal action")
+
+// EnvConfig は Env の設定
+type EnvConfig struct {
+	Players int // 2-4人。0なら2人
+	Seat    int // エージェントの席
+	// Opponent returns the strategy of the opponent at seat.
+	// nil means CommStrategy.
+	Opponent func(seat int, rng *rand.Rand) PlayerStrategy
+}
+
+// Env は強化学習用に1つの席からゲームを進める環境。
+// エージェントの判断待ちになるまで相手の席は戦略で進め、
+// 観測は固定長の数値ベクトル、行動は全ての手を並べた固定の番号で表す。
+// 対象の席はエージェントからの相対位置で番号を付ける。
+type Env struct {
+	conf    EnvConfig
+	game    *Game
+	actions []Action // 番号→相対位置の手
+	index   map[Action]int
+}
+
+// NewEnv returns an error if the number of players or the seat is out of range
+func NewEnv(conf EnvConfig) (*Env, error) {
+	if conf.Players == 0 {
+		conf.Players = 2
+	}
+	if conf.Players < 2 || conf.Players > envMaxPlayers {
+		return nil, fmt.Errorf("env: unsupported number of players: %d", conf.Players)
+	}
+	if conf.Seat < 0 || conf.Seat >= conf.Players {
+		return nil, fmt.Errorf("env: seat %d is out of %d players", conf.Seat, conf.Players)
+	}
+	e := &Env{conf: conf, index: map[Action]int{}}
+	add := func(a Action) {
+		e.index[a] = len(e.actions)
+		e.actions = append(e.actions, a)
+	}
+	for c := 1; c <= 10; c++ {
+		add(NewDiscardAction(c, -1, 0))
+		for t := 1; t < conf.Players; t++ {
+			if c == 2 {
+				for expect := 1; expect <= 10; expect++ {
+					add(NewDiscardAction(c, t, expect))
+				}
+				continue
+			}
+			add(NewDiscardAction(c, t, 0))
+		}
+	}
+	for c := 1; c <= 10; c++ {
+		add(NewWiseAction(c))
+	}
+	for c := 1; c <= 10; c++ {
+		add(NewPublicExecutionAction(c))
+	}
+	add(NewPlagueAction(0))
+	add(NewPlagueAction(1))
+	return e, nil
+}
+
+// ActionSize returns the number of actions
+func (e *Env) ActionSize() int {
+	return len(e.actions)
+}
+
+// Action returns the action of index i for the current game
+func (e *Env) Action(i int) Action {
+	return absoluteAction(e.actions[i], e.conf.Seat, e.conf.Players)
+}
+
+// Game returns the current game
+func (e *Env) Game() *Game {
+	return e.game
+}
+
+// Done returns true if the game is over.
+// The agent may drop out before its first decision, so check it after Reset too.
+func (e *Env) Done() bool {
+	return e.game != nil && e.game.Over()
+}
+
+// Reset deals a new game with seed and proceeds to the first decision of the agent
+func (e *Env) Reset(seed int64) (obs []float64, mask []bool) {
+	rng := rand.New(rand.NewSource(seed))
+	e.game = newSeededGame(e.conf.Players, rng)
+	for i, p := range e.game.Players {
+		if i == e.conf.Seat {
+			continue
+		}
+		if e.conf.Opponent != nil {
+			p.strategy = e.conf.Opponent(i, rng)
+		} else {
+			p.strategy = NewCommStrategy(rng)
+		}
+	}
+	e.game.Start()
+	e.proceed()
+	return e.Observation(), e.Mask()
+}
+
+// Step applies the action of index i. reward is 1 if the agent wins, -1 if it loses
+// and 0 otherwise, given only when done.
+func (e *Env) Step(i int) (obs []float64, mask []bool, reward float64, done bool, err error) {
+	if e.game == nil || e.game.Over() || i < 0 || i >= len(e.actions) {
+		return nil, nil, 0, e.Done(), ErrIllegalAction
+	}
+	if !e.Mask()[i] {
+		return nil, nil, 0, false, ErrIllegalAction
+	}
+	if err := e.game.Step(e.Action(i)); err != nil {
+		return nil, nil, 0, false, err
+	}
+	e.proceed()
+	if e.game.Over() {
+		reward = cfrUtility(e.game, e.conf.Seat)
+	}
+	return e.Observation(), e.Mask(), reward, e.game.Over(), nil
+}
+
+// エージェントの判断待ちか終了まで相手を進める
+func (e *Env) proceed() {
+	g := e.game
+	for !g.Over() && g.Pending().Seat != e.conf.Seat {
+		if err := g.Step(g.ask(context.Background(), g.Pending())); err != nil {
+			log.Fatalf("%s: %v", g.Players[g.Pending().Seat].Name(), err)
+		}
+	}
+}
+
+// Mask returns legal actions of the agent
+func (e *Env) Mask() []bool {
+	mask := make([]bool, len(e.actions))
+	if e.game == nil || e.game.Over() {
+		return mask
+	}
+	v := e.game.View(e.game.Players[e.conf.Seat])
+	for _, a := range LegalActions(v) {
+		if i, ok := e.index[relativeAction(a, v.Seat, len(v.Players))]; ok {
+			mask[i] = true
+		}
+	}
+	return mask
+}
+
+// Observation returns the agent's view as a vector of EnvObservationSize
+func (e *Env) Observation() []float64 {
+	if e.game == nil {
+		return make([]float64, EnvObservationSize)
+	}
+	return Observe(e.game.View(e.game.Players[e.conf.Seat]))
+}
+
+// Observe returns v as a vector of EnvObservationSize. 席はvの視点から順に並べる。
+// 席は4つ分しかないので、vは4人までのゲームでなければならない。
+//
+//	[0,10)    手札のカード(1-10)ごとの枚数/2
+//	[10,14)   判断の種類 (捨てる、賢者、公開処刑、疫病)
+//	[14,24)   賢者の候補・公開処刑で見える手札のカードごとの枚数/2
+//	[24,28)   山札の枚数/17、転生札が残っているか、少年が出たか、公開処刑・疫病を発動したカード/10
+//	[28,32)   公開処刑・疫病の対象の席
+//	[32,132)  席ごとに25個 (いるか、脱落、守護、賢者、手札の枚数/2、
+//	          知っている手札のカード10個、捨てたカードごとの枚数/2 10個)
+func Observe(v PlayerView) []float64 {
+	obs := make([]float64, EnvObservationSize)
+	n := len(v.Players)
+	o := obs
+
+	// 手札
+	for _, c := range v.Hand {
+		o[c-1] += 0.5
+	}
+	o = o[10:]
+
+	// 判断の種類と候補
+	if v.Decision != DecisionNone {
+		o[int(v.Decision)-1] = 1
+	}
+	o = o[4:]
+	for _, c := range v.Candidates {
+		o[c-1] += 0.5
+	}
+	o = o[10:]
+
+	o[0] = float64(v.DeckCount) / float64(len(AllCards)-1)
+	if v.Reincarnation {
+		o[1] = 1
+	}
+	if v.BoyAppeared {
+		o[2] = 1
+	}
+	o[3] = float64(v.Trigger) / 10
+	o = o[4:]
+
+	// 公開処刑・疫病の対象
+	if v.Target >= 0 {
+		o[(v.Target-v.Seat+n)%n] = 1
+	}
+	o = o[envMaxPlayers:]
+
+	for i := 0; i < n; i++ {
+		seat := (v.Seat + i) % n
+		pp := v.Players[seat]
+		s := o[i*envSeatSize:]
+		s[0] = 1
+		if pp.Dropped {
+			s[1] = 1
+		}
+		if pp.Protected {
+			s[2] = 1
+		}
+		if pp.CalledWise {
+			s[3] = 1
+		}
+		s[4] = float64(pp.HandCount) / 2
+		if c, ok := v.Known[seat]; ok {
+			s[5+c-1] = 1
+		}
+		for _, c := range pp.Discarded {
+			s[15+c-1] += 0.5
+		}
+	}
+	return obs
+}
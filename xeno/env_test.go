@@ -0,0 +1,82 @@
+package xeno
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestEnv(t *testing.T) {
+	for players := 2; players <= 4; players++ {
+		e, err := NewEnv(EnvConfig{
+			Players: players,
+			Seat:    players - 1,
+			Opponent: func(seat int, rng *rand.Rand) PlayerStrategy {
+				return NewExpertStrategy(ExpertConfig{Seed: rng.Int63()})
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rng := rand.New(rand.NewSource(int64(players)))
+		for i := 0; i < 20; i++ {
+			obs, mask := e.Reset(int64(i))
+			done := e.Done()
+			for !done {
+				if len(obs) != EnvObservationSize || len(mask) != e.ActionSize() {
+					t.Fatalf("unexpected size: %d %d", len(obs), len(mask))
+				}
+				var legal []int
+				for a, ok := range mask {
+					if ok {
+						legal = append(legal, a)
+					}
+				}
+				if len(legal) == 0 {
+					t.Fatalf("no legal action")
+				}
+				var reward float64
+				var err error
+				obs, mask, reward, done, err = e.Step(legal[rng.Intn(len(legal))])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !done && reward != 0 {
+					t.Errorf("reward before the end: %v", reward)
+				}
+			}
+		}
+	}
+}
+
+func TestNewEnv_Invalid(t *testing.T) {
+	for _, conf := range []EnvConfig{
+		{Players: 1},
+		{Players: envMaxPlayers + 1},
+		{Players: 3, Seat: 3},
+		{Seat: -1},
+	} {
+		if _, err := NewEnv(conf); err == nil {
+			t.Errorf("%+v: want: error", conf)
+		}
+	}
+}
+
+func TestEnv_Reset(t *testing.T) {
+	e, _ := NewEnv(EnvConfig{})
+	obs, mask := e.Reset(1)
+	// 同じシードなら同じ状態になる
+	obs2, mask2 := e.Reset(1)
+	if !reflect.DeepEqual(obs, obs2) || !reflect.DeepEqual(mask, mask2) {
+		t.Errorf("Reset should be deterministic")
+	}
+
+	for i, ok := range mask {
+		if !ok {
+			if _, _, _, _, err := e.Step(i); err != ErrIllegalAction {
+				t.Errorf("want: %v, got: %v", ErrIllegalAction, err)
+			}
+			break
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package xeno
+
+import "fmt"
+
+// EventType は全員に公開される出来事の種類
+type EventType int
+
+const (
+	EventDraw          EventType = iota + 1 // 山札から1枚引く
+	EventWise                               // 賢者で3枚から1枚選ぶ
+	EventDiscard                            // 自分で1枚捨てる
+	EventForcedDiscard                      // 疫病・公開処刑で捨てさせられる
+	EventReveal                             // 公開処刑で残りの手札が公開される
+	EventClairvoyance                       // 透視で手札を見る
+	EventInvestigation                      // 捜査の結果
+	EventExchange                           // 手札を交換する
+	EventConfrontation                      // 対決の結果
+	EventGuarded                            // 守護で効果が無効になる
+	EventReincarnation                      // 転生札で復帰する
+	EventDropout                            // 脱落する
+)
+
+var eventNames = map[EventType]string{
+	EventDraw:          "draw",
+	EventWise:          "wise",
+	EventDiscard:       "discard",
+	EventForcedDiscard: "forced-discard",
+	EventReveal:        "reveal",
+	EventClairvoyance:  "clairvoyance",
+	EventInvestigation: "investigation",
+	EventExchange:      "exchange",
+	EventConfrontation: "confrontation",
+	EventGuarded:       "guarded",
+	EventReincarnation: "reincarnation",
+	EventDropout:       "dropout",
+}
+
+func (t EventType) String() string {
+	return eventNames[t]
+}
+
+// Event は全員が知ることのできる出来事。席はGame.Playersのindex
+type Event struct {
+	Type   EventType
+	Turn   int
+	Seat   int // 出来事の主体
+	Target int // 対象の席。なければ-1
+	Card   int // 捨てた・公開されたカード、脱落の原因のカード、対決で負けた側のカード
+	Expect int // 捜査の予想
+	Hit    bool
+	Winner int // 対決の勝者の席。引き分けは-1
+}
+
+func (e Event) String() string {
+	switch e.Type {
+	case EventDiscard, EventForcedDiscard, EventReveal, EventDropout:
+		return fmt.Sprintf("%v seat:%d card:%d", e.Type, e.Seat, e.Card)
+	case EventInvestigation:
+		return fmt.Sprintf("%v seat:%d target:%d expect:%d hit:%v", e.Type, e.Seat, e.Target, e.Expect, e.Hit)
+	case EventConfrontation:
+		return fmt.Sprintf("%v seat:%d target:%d winner:%d", e.Type, e.Seat, e.Target, e.Winner)
+	case EventClairvoyance, EventExchange, EventGuarded:
+		return fmt.Sprintf("%v seat:%d target:%d", e.Type, e.Seat, e.Target)
+	}
+	return fmt.Sprintf("%v seat:%d", e.Type, e.Seat)
+}
+
+// EventObserver receives public events of a game.
+// Strategies implementing it are notified automatically.
+type EventObserver interface {
+	OnEvent(g *Game, e Event)
+}
+
+// AddObserver registers o to receive events of g. Observers are not copied by Clone.
+func (g *Game) AddObserver(o EventObserver) {
+	g.observers = append(g.observers, o)
+}
+
+func (g *Game) emit(e Event) {
+	e.Turn = g.turn
+	for _, p := range g.Players {
+		if o, ok := p.strategy.(EventObserver); ok {
+			o.OnEvent(g, e)
+		}
+	}
+	for _, o := range g.observers {
+		o.OnEvent(g, e)
+	}
+}
@@ -0,0 +1,279 @@
+package xeno
+
+import (
+	"math"
+	"math/rand"
+)
+
+// ExpertConfig は ExpertStrategy の判断の重み
+type ExpertConfig struct {
+	Keep          float64 // 残すカードの大きさ(最後の比較)の重み
+	KeepEndgame   float64 // 山札が少ないほどKeepに足す重み
+	Investigation float64 // 捜査が当たる確率の重み
+	Clairvoyance  float64 // 透視で得る情報量の重み
+	Guard         float64 // 守護の価値
+	Plague        float64 // 疫病の価値
+	Confront      float64 // 対決の勝ち負けの確率の差の重み
+	ConfrontMin   float64 // 対決の勝率がこれ未満なら対決しない
+	Wise          float64 // 賢者の価値
+	Exchange      float64 // 交換で得られるカードの大きさの差の重み
+	Execution     float64 // 公開処刑で英雄を落とす確率の重み
+	Boy           float64 // 少年1枚目の価値 (革命を相手に渡すので負にする)
+	Temperature   float64 // 0なら常に最善手、大きいほどランダムに選ぶ
+	Seed          int64
+}
+
+// 重みの名前(フィールド名を小文字にしたもの)→フィールド
+func (c *ExpertConfig) weights() map[string]*float64 {
+	return map[string]*float64{
+		"keep":          &c.Keep,
+		"keependgame":   &c.KeepEndgame,
+		"investigation": &c.Investigation,
+		"clairvoyance":  &c.Clairvoyance,
+		"guard":         &c.Guard,
+		"plague":        &c.Plague,
+		"confront":      &c.Confront,
+		"confrontmin":   &c.ConfrontMin,
+		"wise":          &c.Wise,
+		"exchange":      &c.Exchange,
+		"execution":     &c.Execution,
+		"boy":           &c.Boy,
+	}
+}
+
+// ExpertPresets are ExpertConfig for graded difficulty and play style
+var ExpertPresets = map[string]ExpertConfig{
+	"normal": {
+		Keep: 0.2, KeepEndgame: 0.4, Investigation: 6, Clairvoyance: 1, Guard: 0.8, Plague: 0.8,
+		Confront: 6, ConfrontMin: 0.6, Wise: 1.2, Exchange: 0.4, Execution: 8, Boy: -0.5,
+	},
+	"aggressive": {
+		Keep: 0.1, KeepEndgame: 0.3, Investigation: 8, Clairvoyance: 0.5, Guard: 0.3, Plague: 1.2,
+		Confront: 8, ConfrontMin: 0.5, Wise: 1, Exchange: 0.4, Execution: 10, Boy: -0.3,
+	},
+	"cautious": {
+		Keep: 0.3, KeepEndgame: 0.6, Investigation: 5, Clairvoyance: 1.5, Guard: 1.5, Plague: 0.5,
+		Confront: 5, ConfrontMin: 0.75, Wise: 1.5, Exchange: 0.5, Execution: 6, Boy: -0.8,
+	},
+	"easy": {
+		Keep: 0.2, KeepEndgame: 0.4, Investigation: 6, Clairvoyance: 1, Guard: 0.8, Plague: 0.8,
+		Confront: 6, ConfrontMin: 0.6, Wise: 1.2, Exchange: 0.4, Execution: 8, Boy: -0.5,
+		Temperature: 3,
+	},
+}
+
+// ExpertStrategy はBeliefで相手の手札を推定し、カードごとの経験則で判断する
+type ExpertStrategy struct {
+	conf   ExpertConfig
+	rng    *rand.Rand
+	belief *Belief
+	game   *Game // 直近に通知されたゲーム。公開処刑・疫病の判断で使う
+}
+
+func NewExpertStrategy(conf ExpertConfig) *ExpertStrategy {
+	return &ExpertStrategy{
+		conf: conf,
+		rng:  rand.New(rand.NewSource(conf.Seed)),
+	}
+}
+
+func (s *ExpertStrategy) Clone() PlayerStrategy {
+	conf := s.conf
+	conf.Seed = s.rng.Int63()
+	c := NewExpertStrategy(conf)
+	if s.belief != nil {
+		c.belief = s.belief.Clone()
+	}
+	c.game = s.game
+	return c
+}
+
+func (s *ExpertStrategy) OnEvent(g *Game, e Event) {
+	s.game = g
+	if s.belief == nil {
+		seat := strategySeat(g, s)
+		if seat < 0 {
+			return
+		}
+		s.belief = NewBelief(seat)
+	}
+	s.belief.OnEvent(g, e)
+}
+
+func (s *ExpertStrategy) beliefFor(g *Game, seat int) *Belief {
+	if s.belief == nil || s.belief.Seat() != seat {
+		s.belief = NewBelief(seat)
+		s.belief.Update(g.View(g.Players[seat]), Event{Seat: seat})
+	}
+	return s.belief
+}
+
+func (s *ExpertStrategy) SelectDiscard(g *Game, p *Player) CardEvent {
+	s.game = g
+	v := g.discardView(p)
+	b := s.beliefFor(g, v.Seat)
+	actions := LegalActions(v)
+	scores := make([]float64, len(actions))
+	for i, a := range actions {
+		scores[i] = s.score(v, b, a)
+	}
+	return g.actionEvent(actions[s.choose(scores)])
+}
+
+func (s *ExpertStrategy) SelectFromWise(g *Game, candidates []int) int {
+	s.game = g
+	seat := g.pending.Seat
+	v := g.View(g.Players[seat])
+	b := s.beliefFor(g, seat)
+
+	// 選んだ後の2枚で一番良い手が打てるカードを選ぶ
+	cards := uniqueCards(candidates)
+	scores := make([]float64, len(cards))
+	for i, c := range cards {
+		hv := v
+		hv.Decision = DecisionDiscard
+		hv.Hand = append(append([]int{}, v.Hand...), c)
+		hv.Candidates = nil
+		scores[i] = math.Inf(-1)
+		for _, a := range LegalActions(hv) {
+			scores[i] = math.Max(scores[i], s.score(hv, b, a))
+		}
+	}
+	return cards[s.choose(scores)]
+}
+
+func (s *ExpertStrategy) SelectOnPublicExecution(player, target *Player, hand Hand) int {
+	// 英雄がいれば落とす。それ以外は大きい方を捨てさせる
+	if hand.Has(10) {
+		return 10
+	}
+	return hand.Larger()
+}
+
+func (s *ExpertStrategy) SelectOnPlague(player, target *Player, hand Hand) int {
+	// 見えないので左右どちらでも同じ
+	return hand.At(s.rng.Intn(hand.Count()))
+}
+
+func (s *ExpertStrategy) KnowByClairvoyance(g *Game, player, target *Player, c int) {
+	s.game = g
+}
+
+func (s *ExpertStrategy) OnOpponentEvent(g *Game, player, opponent *Player, e CardEvent) {
+	s.game = g
+}
+
+// Temperatureに応じて選ぶ。0なら最大のもの
+func (s *ExpertStrategy) choose(scores []float64) int {
+	best := 0
+	for i := range scores {
+		if scores[i] > scores[best] {
+			best = i
+		}
+	}
+	if s.conf.Temperature <= 0 {
+		return best
+	}
+	weights := make([]float64, len(scores))
+	sum := 0.0
+	for i, sc := range scores {
+		weights[i] = math.Exp((sc - scores[best]) / s.conf.Temperature)
+		sum += weights[i]
+	}
+	r := s.rng.Float64() * sum
+	for i, w := range weights {
+		if r < w {
+			return i
+		}
+		r -= w
+	}
+	return best
+}
+
+// 捨てるカードの効果と残すカードで手を評価する
+func (s *ExpertStrategy) score(v PlayerView, b *Belief, a Action) float64 {
+	c := s.conf
+	discard := a.Card()
+	keep := anotherCard(v.Hand, discard)
+
+	progress := 1 - float64(v.DeckCount)/float64(len(AllCards)-1)
+	keepWeight := c.Keep + c.KeepEndgame*progress
+	value := keepWeight * float64(keep)
+
+	var target Distribution
+	protected := false
+	if a.Target() >= 0 {
+		target = b.Hand(a.Target())
+		protected = v.Players[a.Target()].Protected
+	}
+
+	switch discard {
+	case 1:
+		if !v.BoyAppeared {
+			value += c.Boy
+		} else if !protected {
+			value += c.Execution * target.Prob(10)
+		}
+	case 2:
+		value += c.Investigation * target.Prob(a.Expect())
+	case 3:
+		value += c.Clairvoyance * entropy(target)
+	case 4:
+		value += c.Guard
+	case 5:
+		if !protected {
+			value += c.Plague
+		}
+	case 6:
+		win, lose := 0.0, 0.0
+		for t := 1; t <= 10; t++ {
+			if t < keep {
+				win += target[t]
+			} else if t > keep {
+				lose += target[t]
+			}
+		}
+		if win < c.ConfrontMin {
+			value -= c.Confront
+		} else {
+			value += c.Confront * (win - lose)
+		}
+	case 7:
+		value += c.Wise
+	case 8:
+		// 交換した後は相手のカードを持つことになる
+		expected := 0.0
+		for t := 1; t <= 10; t++ {
+			expected += float64(t) * target[t]
+		}
+		value += (keepWeight + c.Exchange) * (expected - float64(keep))
+	case 9:
+		if !protected {
+			value += c.Execution * target.Prob(10)
+		}
+	}
+	return value
+}
+
+func anotherCard(hand []int, discard int) int {
+	for i, c := range hand {
+		if c == discard {
+			for j, o := range hand {
+				if j != i {
+					return o
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func entropy(d Distribution) float64 {
+	e := 0.0
+	for c := 1; c <= 10; c++ {
+		if d[c] > 0 {
+			e -= d[c] * math.Log2(d[c])
+		}
+	}
+	return e
+}
@@ -0,0 +1,60 @@
+package xeno
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestExpertStrategy_SelectDiscard(t *testing.T) {
+	tests := []struct {
+		name  string
+		hand  []int
+		known int
+		want  CardEvent
+	}{
+		{"confront when it wins", []int{6, 5}, 3, CardEvent{Card: 6}},
+		{"do not confront when it loses", []int{6, 1}, 9, CardEvent{Card: 1}},
+		{"investigate known card", []int{2, 8}, 7, CardEvent{Card: 2, Expect: 7}},
+		{"do not exchange a strong card away", []int{8, 9}, 2, CardEvent{Card: 9}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewExpertStrategy(ExpertPresets["normal"])
+			p := &Player{id: 1, name: "A", hand: Hand{cards: tt.hand}, strategy: s}
+			o := &Player{id: 2, name: "B", hand: Hand{cards: []int{tt.known}}, discarded: []int{}}
+			p.know(o.ID(), tt.known)
+			g := &Game{
+				Deck:        &Deck{cards: []int{1, 2, 3, 4, 5}, reincCard: 4},
+				Players:     []*Player{p, o},
+				boyAppeared: true,
+			}
+			got := s.SelectDiscard(g, p)
+			if got.Card != tt.want.Card || (tt.want.Expect != 0 && got.Expect != tt.want.Expect) {
+				t.Errorf("want: %v, got: %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExpertStrategy_BeatsCommStrategy(t *testing.T) {
+	games := 200
+	wins := 0
+	for i := 0; i < games; i++ {
+		// 配り方と相手の手は乱数の種で決まる
+		rng := rand.New(rand.NewSource(int64(i)))
+		g := newSeededGame(2, rng)
+		seat := i % 2
+		g.Players[1-seat].strategy = NewCommStrategy(rng)
+		conf := ExpertPresets["normal"]
+		conf.Seed = int64(i)
+		g.Players[seat].strategy = NewExpertStrategy(conf)
+		g.Loop()
+		if !g.Players[seat].Dropped() {
+			wins++
+		}
+	}
+	if wins*2 <= games {
+		t.Errorf("ExpertStrategy won only %d/%d games against CommStrategy", wins, games)
+	}
+}
@@ -0,0 +1,455 @@
+package xeno
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"time"
+)
+
+// BotProtocolVersion is the version of the bot protocol.
+//
+// 外部ボットとは標準入出力で1行1つのJSON(BotMessage)をやりとりする。
+// 席は0から始まるGame.Playersのindex、手はAction.Stringの形式 ("d5>1", "d2>1?7", "w7", "x10", "p0")。
+//
+//	engine → bot  {"type":"hello","protocol":1}
+//	bot → engine  {"type":"ready","name":"mybot"}
+//	engine → bot  {"type":"event","event":{...}}                       公開された出来事
+//	engine → bot  {"type":"decide","id":1,"view":{...},"legal":[...]}  判断の要求
+//	bot → engine  {"type":"action","id":1,"action":"d5>1"}             idは要求と同じもの
+//	engine → bot  {"type":"bye"}                                        終了
+//
+// 制限時間内に合法手が返らなければ、その判断は代わりの戦略が行う。
+// ボットが異常終了した後は全ての判断を代わりの戦略が行う。
+const BotProtocolVersion = 1
+
+// BotMessage は外部ボットとやりとりする1行
+type BotMessage struct {
+	Type     string    `json:"type"`
+	Protocol int       `json:"protocol,omitempty"`
+	Name     string    `json:"name,omitempty"`
+	ID       int       `json:"id,omitempty"`
+	Event    *BotEvent `json:"event,omitempty"`
+	View     *BotView  `json:"view,omitempty"`
+	Legal    []string  `json:"legal,omitempty"`
+	Action   string    `json:"action,omitempty"`
+}
+
+// BotEvent は Event のJSON表現
+type BotEvent struct {
+	Type   string `json:"type"`
+	Turn   int    `json:"turn"`
+	Seat   int    `json:"seat"`
+	Target int    `json:"target"`
+	Card   int    `json:"card,omitempty"`
+	Expect int    `json:"expect,omitempty"`
+	Hit    bool   `json:"hit,omitempty"`
+	Winner int    `json:"winner"`
+}
+
+// BotPlayer は PublicPlayer のJSON表現
+type BotPlayer struct {
+	Name       string `json:"name"`
+	HandCount  int    `json:"hand_count"`
+	Discarded  []int  `json:"discarded"`
+	Dropped    bool   `json:"dropped"`
+	Protected  bool   `json:"protected"`
+	CalledWise bool   `json:"called_wise"`
+}
+
+// BotView は PlayerView のJSON表現
+type BotView struct {
+	Seat          int            `json:"seat"`
+	Turn          int            `json:"turn"`
+	Hand          []int          `json:"hand"`
+	Known         map[string]int `json:"known"` // 席→カード
+	Players       []BotPlayer    `json:"players"`
+	DeckCount     int            `json:"deck_count"`
+	Reincarnation bool           `json:"reincarnation"`
+	BoyAppeared   bool           `json:"boy_appeared"`
+	Decision      string         `json:"decision"`
+	Target        int            `json:"target"`
+	Trigger       int            `json:"trigger"`
+	Candidates    []int          `json:"candidates"`
+}
+
+func newBotEvent(e Event) *BotEvent {
+	return &BotEvent{
+		Type:   e.Type.String(),
+		Turn:   e.Turn,
+		Seat:   e.Seat,
+		Target: e.Target,
+		Card:   e.Card,
+		Expect: e.Expect,
+		Hit:    e.Hit,
+		Winner: e.Winner,
+	}
+}
+
+func newBotView(v PlayerView) *BotView {
+	bv := &BotView{
+		Seat:          v.Seat,
+		Turn:          v.Turn,
+		Hand:          v.Hand,
+		Known:         map[string]int{},
+		DeckCount:     v.DeckCount,
+		Reincarnation: v.Reincarnation,
+		BoyAppeared:   v.BoyAppeared,
+		Decision:      v.Decision.String(),
+		Target:        v.Target,
+		Trigger:       v.Trigger,
+		Candidates:    v.Candidates,
+	}
+	for s, c := range v.Known {
+		bv.Known[fmt.Sprint(s)] = c
+	}
+	for _, pp := range v.Players {
+		bv.Players = append(bv.Players, BotPlayer(pp))
+	}
+	return bv
+}
+
+// PlayerView returns v as PlayerView
+func (v *BotView) PlayerView() PlayerView {
+	pv := PlayerView{
+		Seat:          v.Seat,
+		Turn:          v.Turn,
+		Hand:          v.Hand,
+		Known:         map[int]int{},
+		DeckCount:     v.DeckCount,
+		Reincarnation: v.Reincarnation,
+		BoyAppeared:   v.BoyAppeared,
+		Target:        v.Target,
+		Trigger:       v.Trigger,
+		Candidates:    v.Candidates,
+	}
+	for s, c := range v.Known {
+		var seat int
+		if _, err := fmt.Sscan(s, &seat); err == nil {
+			pv.Known[seat] = c
+		}
+	}
+	for _, bp := range v.Players {
+		pv.Players = append(pv.Players, PublicPlayer(bp))
+	}
+	for t := DecisionNone; t <= DecisionPlague; t++ {
+		if t.String() == v.Decision {
+			pv.Decision = t
+		}
+	}
+	return pv
+}
+
+var (
+	ErrBotTimeout = errors.New("bot timed out")
+	ErrBotCrashed = errors.New("bot crashed")
+)
+
+// ExternalConfig は ExternalStrategy の設定
+type ExternalConfig struct {
+	Command  string
+	Args     []string
+	Timeout  time.Duration  // 1回の判断の制限時間。0なら5秒
+	Stderr   io.Writer      // ボットの標準エラー。nilなら捨てる
+	Fallback PlayerStrategy // 時間切れ・異常終了のとき、それ以降に判断する戦略。nilならRandomStrategy
+}
+
+const defaultBotTimeout = 5 * time.Second
+
+// ExternalStrategy は別プロセスのボットにBotProtocolVersionのプロトコルで判断させる
+type ExternalStrategy struct {
+	conf  ExternalConfig
+	name  string
+	cmd   *exec.Cmd
+	out   chan []byte // ボットへ送る行
+	lines chan BotMessage
+	id    int
+	err   error // 最初に起きた異常
+	dead  bool
+	game  *Game // 直近に通知されたゲーム。公開処刑・疫病の判断で使う
+}
+
+// NewExternalStrategy starts the bot and waits for its handshake
+func NewExternalStrategy(conf ExternalConfig) (*ExternalStrategy, error) {
+	if conf.Timeout <= 0 {
+		conf.Timeout = defaultBotTimeout
+	}
+	if conf.Stderr == nil {
+		conf.Stderr = ioutil.Discard
+	}
+	if conf.Fallback == nil {
+		conf.Fallback = RandomStrategy{}
+	}
+
+	cmd := exec.Command(conf.Command, conf.Args...)
+	cmd.Stderr = conf.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	s := &ExternalStrategy{
+		conf:  conf,
+		cmd:   cmd,
+		out:   make(chan []byte, 1024),
+		lines: make(chan BotMessage, 16),
+	}
+	// Closeがs.outを消すので、チャネルは引数で渡す
+	go func(out <-chan []byte) {
+		w := bufio.NewWriter(stdin)
+		for b := range out {
+			w.Write(b)
+			w.Flush()
+		}
+		stdin.Close()
+	}(s.out)
+	go func() {
+		defer close(s.lines)
+		sc := bufio.NewScanner(stdout)
+		sc.Buffer(nil, 1<<20)
+		for sc.Scan() {
+			var m BotMessage
+			if err := json.Unmarshal(sc.Bytes(), &m); err != nil {
+				continue
+			}
+			s.lines <- m
+		}
+	}()
+
+	s.send(BotMessage{Type: "hello", Protocol: BotProtocolVersion})
+	m, err := s.receive(context.Background(), func(m BotMessage) bool { return m.Type == "ready" })
+	if err != nil {
+		s.Close()
+		return nil, fmt.Errorf("handshake: %v", err)
+	}
+	s.name = m.Name
+	return s, nil
+}
+
+// Name returns the name the bot told in the handshake
+func (s *ExternalStrategy) Name() string {
+	return s.name
+}
+
+// Err returns the first error of the bot, such as a timeout, a crash or an illegal action
+func (s *ExternalStrategy) Err() error {
+	return s.err
+}
+
+// Close tells the bot to quit and kills it if it does not exit in the timeout
+func (s *ExternalStrategy) Close() error {
+	if s.out == nil {
+		return nil
+	}
+	if !s.dead {
+		s.send(BotMessage{Type: "bye"})
+	}
+	close(s.out)
+	s.out = nil
+	// 標準出力を読み終えてからWaitする
+	done := make(chan struct{})
+	go func() {
+		for range s.lines {
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(s.conf.Timeout):
+		s.cmd.Process.Kill()
+		<-done
+	}
+	return s.cmd.Wait()
+}
+
+// 時間切れや異常終了したボットにはもう聞かない
+func (s *ExternalStrategy) fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+	if err == ErrBotCrashed || err == ErrBotTimeout {
+		s.dead = true
+	}
+}
+
+func (s *ExternalStrategy) send(m BotMessage) {
+	if s.dead || s.out == nil {
+		return
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return
+	}
+	select {
+	case s.out <- append(b, '\n'):
+	default:
+		// 読まれないまま溜まったら応答しないものとみなす
+		s.fail(ErrBotCrashed)
+	}
+}
+
+// matchを満たす行を制限時間まで待つ。ctxで止めたときはボットを異常とみなさない
+func (s *ExternalStrategy) receive(ctx context.Context, match func(BotMessage) bool) (BotMessage, error) {
+	timeout := time.After(s.conf.Timeout)
+	for {
+		select {
+		case <-ctx.Done():
+			return BotMessage{}, ctx.Err()
+		case m, ok := <-s.lines:
+			if !ok {
+				s.fail(ErrBotCrashed)
+				return BotMessage{}, ErrBotCrashed
+			}
+			if match(m) {
+				return m, nil
+			}
+		case <-timeout:
+			s.fail(ErrBotTimeout)
+			return BotMessage{}, ErrBotTimeout
+		}
+	}
+}
+
+// ボットに判断させる。失敗したらfalse
+func (s *ExternalStrategy) decide(ctx context.Context, g *Game, p *Player, d Decision) (Action, bool) {
+	if s.dead {
+		return 0, false
+	}
+	v := g.view(p, d)
+	var legal []string
+	for _, a := range LegalActions(v) {
+		legal = append(legal, a.String())
+	}
+	s.id++
+	id := s.id
+	s.send(BotMessage{Type: "decide", ID: id, View: newBotView(v), Legal: legal})
+	m, err := s.receive(ctx, func(m BotMessage) bool { return m.Type == "action" && m.ID == id })
+	if err != nil {
+		g.debugf("%s: %v\n", p.Name(), err)
+		return 0, false
+	}
+	a, err := ParseAction(m.Action)
+	if err == nil && !IsLegal(v, a) {
+		err = fmt.Errorf("illegal action %v for %v", a, d.Type)
+	}
+	if err != nil {
+		g.debugf("%s: %v\n", p.Name(), err)
+		s.fail(err)
+		return 0, false
+	}
+	return a, true
+}
+
+func (s *ExternalStrategy) OnEvent(g *Game, e Event) {
+	s.game = g
+	s.send(BotMessage{Type: "event", Event: newBotEvent(e)})
+}
+
+// Decide stops waiting for the bot when ctx is done
+func (s *ExternalStrategy) Decide(ctx context.Context, g *Game, d Decision) Action {
+	s.game = g
+	if a, ok := s.decide(ctx, g, g.Players[d.Seat], d); ok {
+		return a
+	}
+	return askStrategy(ctx, g, s.conf.Fallback, d)
+}
+
+func (s *ExternalStrategy) SelectDiscard(g *Game, p *Player) CardEvent {
+	s.game = g
+	if a, ok := s.decide(context.Background(), g, p, Decision{Type: DecisionDiscard, Seat: g.Seat(p)}); ok {
+		return g.actionEvent(a)
+	}
+	return s.conf.Fallback.SelectDiscard(g, p)
+}
+
+func (s *ExternalStrategy) SelectFromWise(g *Game, candidates []int) int {
+	s.game = g
+	if a, ok := s.decide(context.Background(), g, g.Players[g.pending.Seat], g.pending); ok {
+		return a.Card()
+	}
+	return s.conf.Fallback.SelectFromWise(g, candidates)
+}
+
+func (s *ExternalStrategy) SelectOnPublicExecution(player, target *Player, hand Hand) int {
+	if s.game != nil {
+		if a, ok := s.decide(context.Background(), s.game, player, s.game.pending); ok {
+			return a.Card()
+		}
+	}
+	return s.conf.Fallback.SelectOnPublicExecution(player, target, hand)
+}
+
+func (s *ExternalStrategy) SelectOnPlague(player, target *Player, hand Hand) int {
+	if s.game != nil {
+		if a, ok := s.decide(context.Background(), s.game, player, s.game.pending); ok {
+			return hand.At(a.Index())
+		}
+	}
+	return s.conf.Fallback.SelectOnPlague(player, target, hand)
+}
+
+func (s *ExternalStrategy) KnowByClairvoyance(g *Game, player, target *Player, c int) {
+	s.game = g
+	s.conf.Fallback.KnowByClairvoyance(g, player, target, c)
+}
+
+func (s *ExternalStrategy) OnOpponentEvent(g *Game, player, opponent *Player, e CardEvent) {
+	s.game = g
+	s.conf.Fallback.OnOpponentEvent(g, player, opponent, e)
+}
+
+// BotFunc decides the action of a bot from its view and the legal actions
+type BotFunc func(v PlayerView, legal []Action) Action
+
+// ServeBot speaks the bot protocol as a bot named name on in and out, until "bye" or EOF
+func ServeBot(name string, in io.Reader, out io.Writer, decide BotFunc) error {
+	enc := json.NewEncoder(out)
+	sc := bufio.NewScanner(in)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		var m BotMessage
+		if err := json.Unmarshal(sc.Bytes(), &m); err != nil {
+			return err
+		}
+		switch m.Type {
+		case "hello":
+			if m.Protocol != BotProtocolVersion {
+				return fmt.Errorf("unsupported protocol: %d", m.Protocol)
+			}
+			if err := enc.Encode(BotMessage{Type: "ready", Name: name}); err != nil {
+				return err
+			}
+		case "decide":
+			if m.View == nil {
+				return errors.New("decide without view")
+			}
+			var legal []Action
+			for _, l := range m.Legal {
+				a, err := ParseAction(l)
+				if err != nil {
+					return err
+				}
+				legal = append(legal, a)
+			}
+			a := decide(m.View.PlayerView(), legal)
+			if err := enc.Encode(BotMessage{Type: "action", ID: m.ID, Action: a.String()}); err != nil {
+				return err
+			}
+		case "bye":
+			return nil
+		}
+	}
+	return sc.Err()
+}
@@ -0,0 +1,136 @@
+package xeno
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// XENO_TEST_BOT が設定されていれば、テストのバイナリをボットとして動かす
+func TestMain(m *testing.M) {
+	switch os.Getenv("XENO_TEST_BOT") {
+	case "":
+		os.Exit(m.Run())
+	case "first":
+		ServeBot("first", os.Stdin, os.Stdout, func(v PlayerView, legal []Action) Action {
+			return legal[0]
+		})
+	case "illegal":
+		ServeBot("illegal", os.Stdin, os.Stdout, func(v PlayerView, legal []Action) Action {
+			return NewPlagueAction(5)
+		})
+	case "slow":
+		ServeBot("slow", os.Stdin, os.Stdout, func(v PlayerView, legal []Action) Action {
+			time.Sleep(time.Second)
+			return legal[0]
+		})
+	case "crash":
+		ServeBot("crash", os.Stdin, os.Stdout, func(v PlayerView, legal []Action) Action {
+			os.Exit(1)
+			return 0
+		})
+	case "silent":
+		time.Sleep(10 * time.Second)
+	}
+	os.Exit(0)
+}
+
+func newTestBot(t *testing.T, mode string, timeout time.Duration) (*ExternalStrategy, error) {
+	os.Setenv("XENO_TEST_BOT", mode)
+	defer os.Unsetenv("XENO_TEST_BOT")
+	return NewExternalStrategy(ExternalConfig{Command: os.Args[0], Timeout: timeout})
+}
+
+func playWith(s *ExternalStrategy) *Game {
+	g := NewGame(GameConfig{Players: []PlayerConfig{{Strategy: s}, {StrategyName: "random"}}})
+	g.SetOutput(ioutil.Discard)
+	g.Loop()
+	return g
+}
+
+func TestExternalStrategy(t *testing.T) {
+	s, err := newTestBot(t, "first", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Name() != "first" {
+		t.Errorf("want: first, got: %s", s.Name())
+	}
+	for i := 0; i < 5; i++ {
+		if g := playWith(s); !g.Over() {
+			t.Errorf("game is not over")
+		}
+	}
+	if s.Err() != nil {
+		t.Errorf("unexpected error: %v", s.Err())
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("unexpected error on close: %v", err)
+	}
+}
+
+func TestExternalStrategy_Failures(t *testing.T) {
+	tests := []struct {
+		mode string
+		want error // nilなら他のエラー
+	}{
+		{"slow", ErrBotTimeout},
+		{"crash", ErrBotCrashed},
+		{"illegal", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			s, err := newTestBot(t, tt.mode, 100*time.Millisecond)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			// 代わりの戦略で最後まで進む
+			if g := playWith(s); !g.Over() {
+				t.Errorf("game is not over")
+			}
+			err = s.Err()
+			if err == nil || (tt.want != nil && err != tt.want) || (tt.want == nil && (err == ErrBotTimeout || err == ErrBotCrashed)) {
+				t.Errorf("want: %v, got: %v", tt.want, err)
+			}
+			// 時間切れや異常終了の後はもう聞かない
+			if tt.want != nil && s.id != 1 {
+				t.Errorf("bot is asked %d times", s.id)
+			}
+		})
+	}
+
+	if _, err := newTestBot(t, "silent", 100*time.Millisecond); err == nil {
+		t.Errorf("want: handshake error")
+	}
+}
+
+func TestNewStrategy_External(t *testing.T) {
+	os.Setenv("XENO_TEST_BOT", "first")
+	defer os.Unsetenv("XENO_TEST_BOT")
+
+	spec := "external:cmd=" + os.Args[0] + ",timeout=1s,fallback=random"
+	s, err := NewStrategy(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := s.(*ExternalStrategy); !ok || e.Name() != "first" {
+		t.Errorf("want: external bot first, got: %#v", s)
+	}
+	s.(*ExternalStrategy).Close()
+
+	tr, err := NewTournament(TournamentConfig{Specs: []string{spec, "random"}, Games: 2, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lb := NewLeaderboard()
+	tr.Run(lb)
+	if r := lb.Ratings[spec]; r == nil || r.Games != 4 {
+		t.Errorf("want: 4 games of the bot, got: %+v", r)
+	}
+
+	if _, err := NewStrategy("external:timeout=1s"); err == nil {
+		t.Error("want: error without cmd")
+	}
+}
@@ -3,9 +3,16 @@ package xeno
 //go:generate mockgen -source=game.go -destination=./game_mock.go -package xeno
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"math/rand"
+	"os"
+	"strings"
+	"time"
 )
 
 var (
@@ -57,7 +64,7 @@ type Deck struct {
 }
 
 func newDeck() *Deck {
-	cards := AllCards
+	cards := append([]int{}, AllCards...)
 
 	shuffler := RandomShuffler{}
 	cards = shuffler.Shuffle(cards)
@@ -91,7 +98,7 @@ func (d *Deck) take() int {
 
 func (d *Deck) takeN(n int) []int {
 	var cards []int
-	for i := 0; d.count() > 0 && i < 3; i++ {
+	for i := 0; d.count() > 0 && i < n; i++ {
 		cards = append(cards, d.take())
 	}
 	return cards
@@ -103,6 +110,21 @@ func (d *Deck) takeBack(cards []int) {
 	d.cards = d.shuffler.Shuffle(d.cards)
 }
 
+// SetShuffler replaces the shuffler used after the wise
+func (d *Deck) SetShuffler(s Shuffler) {
+	d.shuffler = s
+}
+
+func (d *Deck) clone() *Deck {
+	c := *d
+	c.cards = append([]int{}, d.cards...)
+	// 状態を持つシャッフラーは複製する
+	if sc, ok := d.shuffler.(interface{ clone() Shuffler }); ok {
+		c.shuffler = sc.clone()
+	}
+	return &c
+}
+
 // 転生
 func (d *Deck) ReincarnateCard() (bool, int) {
 	if d.reincCard == 0 {
@@ -116,6 +138,20 @@ func (d *Deck) ReincarnateCard() (bool, int) {
 
 type GameConfig struct {
 	Players []PlayerConfig
+	// ParamFile は Tune が書いた引数のファイル。戦略を指定していないCOMに使う
+	ParamFile string
+	// DisableUndo は人間の待ったを禁止する。対戦用
+	DisableUndo bool
+	// HotSeat は1つの端末で複数の人間が遊ぶときに、判断する人間の情報だけを表示する
+	HotSeat bool
+	// Audit は手番ごとにカードの数と手札の枚数を確かめる。不整合は Game.AuditErr で分かる
+	Audit bool
+	// DecisionTimeout は1回の判断の制限時間。0なら無制限。PlayerConfig.Timeout が優先する。ContextStrategy にだけ効く
+	DecisionTimeout time.Duration
+	// Fallback は時間切れの判断の代わりの手を決める。nilなら FirstLegalAction
+	Fallback FallbackPolicy
+	// Profiles は通算成績のファイル。PlayerConfig.Profile のあるプレイヤーの成績をゲームの終わりに更新する
+	Profiles string
 }
 
 type Game struct {
@@ -123,20 +159,133 @@ type Game struct {
 	Players     []*Player
 	boyAppeared bool
 	turn        int
+	pending     Decision // 判断待ち
+	over        bool
+	out         io.Writer
+	observers   []EventObserver
+	record      *Record
+	noUndo      bool
+	snapshots   []snapshot // 人間の判断の前の状態
+	hotSeat     bool
+	in          io.Reader
+	line        chan inputLine  // 読みかけの入力の行
+	ctx         context.Context // 進行中のLoopContextなどのctx。判断でない入力の待ちも止める
+	auditCards  map[int]int     // 監査で数えるカード。nilなら監査しない
+	auditErr    error
+	fallback    FallbackPolicy
+	timeouts    []Timeout
+	profileFile string
+	profileKeys []string // 席→通算成績のキー
 }
 
+// 乱数で配ったn人のゲーム。賢者の後のシャッフルも同じ乱数を使う。
+// プレイヤーは同じ乱数のRandomStrategyで、出力は捨てる。
+// 並行して呼べるように、プレイヤーのIDは席+1にする
+func newSeededGame(n int, rng *rand.Rand) *Game {
+	cards := rngShuffler{rng: rng}.Shuffle(append([]int{}, AllCards...))
+	g := &Game{
+		Deck: &Deck{
+			cards:     cards[:len(cards)-1],
+			reincCard: cards[len(cards)-1],
+			shuffler:  rngShuffler{rng: rng},
+		},
+		out: ioutil.Discard,
+	}
+	for i := 0; i < n; i++ {
+		g.Players = append(g.Players, &Player{
+			id:       PlayerID(i + 1),
+			name:     fmt.Sprintf("プレイヤー%d", i+1),
+			hand:     Hand{cards: []int{}},
+			strategy: RandomStrategy{rng: rng},
+		})
+	}
+	return g
+}
+
+// NewGame panics if a strategy of conf can't be built. BuildGame returns the error instead.
 func NewGame(conf GameConfig) *Game {
+	g, err := BuildGame(conf)
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// BuildGame returns an error if a strategy of conf can't be built
+func BuildGame(conf GameConfig) (*Game, error) {
 	deck := newDeck()
 
 	players := make([]*Player, len(conf.Players))
 	for i, c := range conf.Players {
-		players[i] = NewPlayer(c)
+		if conf.ParamFile != "" && c.Strategy == nil && c.StrategyName == "" && !c.Manual {
+			c.StrategyName = "file:" + conf.ParamFile
+		}
+		var err error
+		if players[i], err = BuildPlayer(c); err != nil {
+			return nil, err
+		}
+		if c.Timeout == 0 {
+			players[i].timeout = conf.DecisionTimeout
+		}
+	}
+
+	g := &Game{
+		Deck:     deck,
+		Players:  players,
+		noUndo:   conf.DisableUndo,
+		hotSeat:  conf.HotSeat,
+		fallback: conf.Fallback,
+	}
+	g.SetAudit(conf.Audit)
+	for _, c := range conf.Players {
+		if conf.Profiles != "" && c.Profile != "" {
+			g.profileFile = conf.Profiles
+		}
+		g.profileKeys = append(g.profileKeys, c.Profile)
+	}
+	if g.profileFile != "" {
+		g.StartRecording()
+	} else {
+		g.profileKeys = nil
 	}
+	return g, nil
+}
 
-	return &Game{
-		Deck:    deck,
-		Players: players,
+// Clone returns an independent copy of g for search.
+// Strategies implementing StrategyCloner are cloned, others are shared.
+// The copy neither prints, reads input, audits nor updates profiles.
+func (g *Game) Clone() *Game {
+	c := *g
+	c.Deck = g.Deck.clone()
+	c.Players = make([]*Player, len(g.Players))
+	for i, p := range g.Players {
+		c.Players[i] = p.clone()
+		// 人間の入出力はコピーのものを使う
+		if ms, ok := c.Players[i].strategy.(*ManualStrategy); ok {
+			ms.game = &c
+		}
 	}
+	c.pending.Candidates = append([]int(nil), g.pending.Candidates...)
+	c.timeouts = append([]Timeout(nil), g.timeouts...)
+	c.observers = nil
+	c.record = nil
+	c.snapshots = nil
+	// 探索のコピーが人間の入力を読んだり、成績を書いたりしないように
+	c.out = ioutil.Discard
+	c.in = strings.NewReader("")
+	c.line = nil
+	c.ctx = nil
+	c.hotSeat = false
+	c.auditCards = nil
+	c.auditErr = nil
+	c.profileFile = ""
+	c.profileKeys = nil
+	return &c
+}
+
+// SetOutput sets the destination of game messages. nil means os.Stdout.
+func (g *Game) SetOutput(w io.Writer) {
+	g.out = w
 }
 
 func (g Game) CurrentPlayer() *Player {
@@ -174,90 +323,297 @@ func (g Game) Player(id PlayerID) *Player {
 	return nil
 }
 
+// Pending returns the decision the game is waiting for
+func (g *Game) Pending() Decision {
+	return g.pending
+}
+
+func (g *Game) Over() bool {
+	return g.over
+}
+
+func (g *Game) Turn() int {
+	return g.turn
+}
+
+// Winners returns players who are not dropped after the game is over
+func (g *Game) Winners() []*Player {
+	var winners []*Player
+	if !g.over {
+		return winners
+	}
+	for _, p := range g.Players {
+		if !p.Dropped() {
+			winners = append(winners, p)
+		}
+	}
+	return winners
+}
+
+// Loop plays the game to the end asking the strategies
 func (g *Game) Loop() {
-	fmt.Println("山札:", g.Deck)
-	fmt.Println("プレイヤー数:", len(g.Players))
-
-	for {
-		g.ProcessTurn()
-
-		if g.Deck.finished() {
-			fmt.Println("山札なし")
-			fmt.Println("ゲーム終了")
-			fmt.Println("_/_/_/_/_/_/_/_/_/_/_/_/_/_/_/")
-			var max, maxi int
-			for i, p := range g.Players {
-				if !p.Dropped() {
-					fmt.Printf("%sのカード: %s\n", p.Name(), p.Hand())
-					if max < p.Hand().Get() {
-						max = p.Hand().Get()
-						maxi = i
-					}
-				}
+	g.LoopContext(context.Background())
+}
+
+// Start begins the game without strategies deciding.
+// Decisions are given by Step until Over() returns true.
+func (g *Game) Start() {
+	g.printHeader()
+	g.beginTurn()
+	g.advance()
+}
+
+// Step applies a to the pending decision and proceeds to the next decision.
+// In the audit mode the first violation found is returned.
+func (g *Game) Step(a Action) error {
+	found := g.auditErr != nil
+	if err := g.apply(a); err != nil {
+		return err
+	}
+	g.advance()
+	if !found && g.auditErr != nil {
+		return g.auditErr
+	}
+	return nil
+}
+
+func (g *Game) advance() {
+	for g.pending.Type == DecisionNone && !g.over {
+		g.println("======================================")
+		if g.endTurn() {
+			g.printWinners()
+			return
+		}
+		g.beginTurn()
+	}
+}
+
+// ProcessTurn plays one turn asking the strategies of players
+func (g *Game) ProcessTurn() {
+	g.ProcessTurnContext(context.Background())
+}
+
+// 判断待ちがなくなるまで戦略に聞く。ctxが終われば判断待ちのまま止める
+func (g *Game) resolve(ctx context.Context) error {
+	for g.pending.Type != DecisionNone {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		p := g.Players[g.pending.Seat]
+		snapshots := len(g.snapshots)
+		g.saveSnapshot(p)
+		if g.hotSeat && isHuman(p) {
+			if err := g.passTo(ctx, p); err != nil {
+				g.snapshots = g.snapshots[:snapshots]
+				return err
 			}
-			for i, p := range g.Players {
-				if i != maxi && !p.Dropped() {
-					p.Dropout()
-					fmt.Printf("%s 脱落\n", p.Name())
-				}
+		}
+		a, ok, err := g.askContext(ctx, g.pending)
+		if g.hotSeat && isHuman(p) {
+			g.printf(clearScreen)
+		}
+		if err != nil {
+			g.snapshots = g.snapshots[:snapshots]
+			return err
+		}
+		if !ok {
+			if !g.undo() {
+				g.println("待ったはできません")
 			}
-			break
-		} else if g.AlivePlayerCount() < 2 {
-			fmt.Println("ゲーム終了")
-			break
+			continue
 		}
-		g.turn++
+		if err := g.apply(a); err != nil {
+			log.Fatalf("%s: %v", p.Name(), err)
+		}
+	}
+	return nil
+}
+
+func (g *Game) printHeader() {
+	if g.hotSeat {
+		g.println("山札:", g.Deck.count(), "枚")
+	} else {
+		g.println("山札:", g.Deck)
 	}
+	g.println("プレイヤー数:", len(g.Players))
+}
 
+func (g *Game) printWinners() {
 	for _, p := range g.Players {
 		if !p.Dropped() {
-			fmt.Printf("%s の勝ち!\n", p.Name())
+			g.printf("%s の勝ち!\n", p.Name())
 		}
 	}
-	fmt.Println("_/_/_/_/_/_/_/_/_/_/_/_/_/_/_/")
+	g.println("_/_/_/_/_/_/_/_/_/_/_/_/_/_/_/")
 }
 
-func (g *Game) ProcessTurn() {
-	fmt.Println(g)
-	defer fmt.Println("======================================")
+// ターン終了。ゲームが終わればtrue
+func (g *Game) endTurn() bool {
+	g.audit()
+	if g.Deck.finished() {
+		g.println("山札なし")
+		g.println("ゲーム終了")
+		g.println("_/_/_/_/_/_/_/_/_/_/_/_/_/_/_/")
+		var max, maxi int
+		for i, p := range g.Players {
+			if !p.Dropped() {
+				g.printf("%sのカード: %s\n", p.Name(), p.Hand())
+				if max < p.Hand().Get() {
+					max = p.Hand().Get()
+					maxi = i
+				}
+			}
+		}
+		for i, p := range g.Players {
+			if i != maxi && !p.Dropped() {
+				g.dropout(p, 0)
+				g.printf("%s 脱落\n", p.Name())
+			}
+		}
+		g.over = true
+	} else if g.AlivePlayerCount() < 2 {
+		g.println("ゲーム終了")
+		g.over = true
+	}
+	if g.over {
+		g.updateProfiles()
+		return true
+	}
+	g.turn++
+	return false
+}
+
+// ターン開始。カードを引いて最初の判断待ちにする
+func (g *Game) beginTurn() {
+	defer g.audit()
+	if g.hotSeat {
+		g.println(g.publicString())
+	} else {
+		g.println(g)
+	}
 
 	p := g.CurrentPlayer()
 
-	fmt.Printf("%s の番 \n", p.Name())
+	g.printf("%s の番 \n", p.Name())
 
 	if p.Dropped() {
-		fmt.Printf("%s 脱落 スキップ\n", p.Name())
+		g.printf("%s 脱落 スキップ\n", p.Name())
 		return
 	}
 
-	var next int
 	if p.CalledWise() {
-		fmt.Println("賢者からの選択: ")
+		g.println("賢者からの選択: ")
 		candidates := g.Deck.takeN(3)
 		{
 			str := ""
 			for _, c := range candidates {
 				str += fmt.Sprintf("[%d]", c)
 			}
-			debugPrintf("%s\n", str)
+			g.debugf("%s\n", str)
 		}
-		var remains []int
-		remains = p.TakeFromWise(g, candidates)
-		debugPrintf("[%d]を選択\n", next)
-		g.Deck.takeBack(remains)
-	} else {
-		fmt.Println("山札から引く: ")
-		next = g.Deck.take()
-		p.Take(next)
+		g.pending = Decision{Type: DecisionWise, Seat: g.Seat(p), Candidates: candidates}
+		return
 	}
+
+	g.println("山札から引く: ")
+	next := g.Deck.take()
+	p.Take(next)
+	g.debugf("引いたカード: [%d]\n", next)
+	g.emit(Event{Type: EventDraw, Seat: g.Seat(p), Target: -1})
+	g.beginDiscard(p)
+}
+
+func (g *Game) beginDiscard(p *Player) {
 	p.SetCalledWise(false)
 	p.SetProtected(false)
 
-	debugPrintf("引いたカード: [%d]\n", next)
-	fmt.Println("どちらかを捨てる:")
-	debugPrintf("%s\n", p.Hand())
+	if p.Hand().Count() < 2 {
+		// 最初の1枚を配られただけ
+		return
+	}
+	g.println("どちらかを捨てる:")
+	g.debugf("%s\n", p.Hand())
+	g.pending = Decision{Type: DecisionDiscard, Seat: g.Seat(p)}
+}
+
+// 判断待ちをプレイヤーの戦略に聞く
+func (g *Game) ask(ctx context.Context, d Decision) Action {
+	return askStrategy(ctx, g, g.Players[d.Seat].strategy, d)
+}
+
+// CardEventをActionに変換する。不要な対象・予想は落とす
+func (g *Game) eventAction(e CardEvent) Action {
+	target := -1
+	if e.Target != nil && needsTarget(e.Card, g.boyAppeared) {
+		target = g.Seat(e.Target)
+	}
+	expect := 0
+	if e.Card == 2 {
+		expect = e.Expect
+	}
+	return NewDiscardAction(e.Card, target, expect)
+}
+
+func (g *Game) actionEvent(a Action) CardEvent {
+	e := CardEvent{Card: a.Card(), Expect: a.Expect()}
+	if a.Target() >= 0 {
+		e.Target = g.Players[a.Target()]
+	}
+	return e
+}
+
+// 判断待ちにaを適用する
+func (g *Game) apply(a Action) error {
+	d := g.pending
+	if d.Type == DecisionNone {
+		return errors.New("no pending decision")
+	}
+	p := g.Players[d.Seat]
+	if !IsLegal(g.view(p, d), a) {
+		return fmt.Errorf("illegal action %v for %v", a, d.Type)
+	}
+	g.pending = Decision{}
+	g.recordMove(d.Seat, a)
+
+	switch d.Type {
+	case DecisionWise:
+		g.takeFromWise(p, d.Candidates, a.Card())
+		g.beginDiscard(p)
+	case DecisionDiscard:
+		g.discard(p, g.actionEvent(a))
+	case DecisionPublicExecution:
+		g.executePublicly(p, g.Players[d.Target], a.Card(), d.Trigger == 9)
+	case DecisionPlague:
+		g.executePlague(p, g.Players[d.Target], a.Index())
+	}
+	g.audit()
+	return nil
+}
+
+// 賢者 候補から1枚取り、残りを山札に戻す
+func (g *Game) takeFromWise(p *Player, candidates []int, selected int) {
+	var remains []int
+	found := false
+	for _, c := range candidates {
+		if c == selected && !found {
+			// 見つかった番号1枚目は選択したカードとしてスキップ
+			found = true
+			continue
+		}
+		// 残った2枚をremainsに入れる
+		remains = append(remains, c)
+	}
+	p.Take(selected)
+	g.debugf("[%d]を選択\n", selected)
+	g.Deck.takeBack(remains)
+	g.recordShuffle()
+	g.emit(Event{Type: EventWise, Seat: g.Seat(p), Target: -1})
+}
 
-	event := p.Discard(g)
+func (g *Game) discard(p *Player, event CardEvent) {
+	p.DiscardSpecified(event.Card)
+	g.forgetDiscarded(p, event.Card)
+	g.emit(Event{Type: EventDiscard, Seat: g.Seat(p), Target: g.Seat(event.Target), Card: event.Card, Expect: event.Expect})
 
 	// Notify other players
 	for _, op := range g.OtherPlayers(p) {
@@ -265,153 +621,249 @@ func (g *Game) ProcessTurn() {
 	}
 
 	if event.Card > 0 {
-		fmt.Printf("捨てたカード: [%d] %s\n", event.Card, CardTypes[event.Card])
+		g.printf("捨てたカード: [%d] %s\n", event.Card, CardTypes[event.Card])
 	}
 
 	switch event.Card {
 	case 1:
 		if !g.boyAppeared {
-			fmt.Println("少年1枚目。効果発動なし。")
+			g.println("少年1枚目。効果発動なし。")
 		} else {
-			fmt.Println("少年2枚目。革命。公開処刑が発動。")
+			g.println("少年2枚目。革命。公開処刑が発動。")
 			// 公開処刑
-			g.publicExecution(p, event.Target, false)
+			g.publicExecution(p, event.Target, 1)
 		}
 		g.boyAppeared = true
 	case 2: // 捜査
-		fmt.Printf("捜査の効果: %sは%sに手札を言い当てられると脱落。\n", event.Target.Name(), p.Name())
+		g.printf("捜査の効果: %sは%sに手札を言い当てられると脱落。\n", event.Target.Name(), p.Name())
 		g.investigation(p, event.Target, event.Expect)
 	case 3: // 透視
-		fmt.Printf("透視の効果: %sは%sの手札を見ることができる。\n", p.Name(), event.Target.Name())
+		g.printf("透視の効果: %sは%sの手札を見ることができる。\n", p.Name(), event.Target.Name())
 		c := event.Target.ShowForClairvoyance()
-		p.KnowByClairvoyance(g, event.Target, c)
+		p.know(event.Target.ID(), c)
+		g.privately(p, func() {
+			p.KnowByClairvoyance(g, event.Target, c)
+		})
+		g.emit(Event{Type: EventClairvoyance, Seat: g.Seat(p), Target: g.Seat(event.Target)})
 	case 4: // 守護
-		fmt.Printf("守護の効果: %sは次の手番まで自分への効果が無効。\n", p.Name())
+		g.printf("守護の効果: %sは次の手番まで自分への効果が無効。\n", p.Name())
 		p.SetProtected(true)
 	case 5: // 疫病
-		fmt.Printf("疫病の効果: %sは%sに1枚引かせて、非公開で1枚捨てさせる。\n", p.Name(), event.Target.Name())
+		g.printf("疫病の効果: %sは%sに1枚引かせて、非公開で1枚捨てさせる。\n", p.Name(), event.Target.Name())
 		g.plague(p, event.Target)
 	case 6: // 対決
-		fmt.Printf("対決の効果: %sと%sで手札が小さい方が脱落。\n", p.Name(), event.Target.Name())
+		g.printf("対決の効果: %sと%sで手札が小さい方が脱落。\n", p.Name(), event.Target.Name())
 		g.confrontation(p, event.Target)
 	case 7: // 選択
 		p.SetCalledWise(true)
-		fmt.Printf("選択の効果: %sは次ターンで3枚引く。\n", p.Name())
+		g.printf("選択の効果: %sは次ターンで3枚引く。\n", p.Name())
 	case 8: // 交換
-		fmt.Printf("交換の効果: %sと%sはカードを交換。\n", p.Name(), event.Target.Name())
+		g.printf("交換の効果: %sと%sはカードを交換。\n", p.Name(), event.Target.Name())
 		pc := p.Give()
 		tc := event.Target.Give()
 		p.Take(tc)
 		event.Target.Take(pc)
+		g.exchangeKnowledge(p, event.Target, pc, tc)
+		g.emit(Event{Type: EventExchange, Seat: g.Seat(p), Target: g.Seat(event.Target)})
 	case 9: // 公開処刑
-		fmt.Printf("公開処刑の効果: %sは%sに1枚引かせて、公開し1枚捨てさせる。\n", p.Name(), event.Target.Name())
-		g.publicExecution(p, event.Target, true)
+		g.printf("公開処刑の効果: %sは%sに1枚引かせて、公開し1枚捨てさせる。\n", p.Name(), event.Target.Name())
+		g.publicExecution(p, event.Target, 9)
 	case 10:
 		// 有り得ない
 	}
-	return
 }
 
 // 対決
 func (g *Game) confrontation(executor, target *Player) {
+	e := Event{Type: EventConfrontation, Seat: g.Seat(executor), Target: g.Seat(target)}
 	if executor.Hand().Get() > target.Hand().Get() {
-		fmt.Printf("%s の勝ち\n", executor.Name())
-		target.Dropout()
+		g.printf("%s の勝ち\n", executor.Name())
+		e.Winner, e.Card = e.Seat, target.Hand().Get()
+		g.emit(e)
+		g.dropout(target, 6)
 	} else if executor.Hand().Get() < target.Hand().Get() {
-		fmt.Printf("%s の勝ち\n", target.Name())
-		executor.Dropout()
+		g.printf("%s の勝ち\n", target.Name())
+		e.Winner, e.Card = e.Target, executor.Hand().Get()
+		g.emit(e)
+		g.dropout(executor, 6)
 	} else {
-		fmt.Printf("引き分け\n")
-		target.Dropout()
-		executor.Dropout()
+		g.printf("引き分け\n")
+		e.Winner, e.Card = -1, target.Hand().Get()
+		g.emit(e)
+		g.dropout(target, 6)
+		g.dropout(executor, 6)
 	}
 }
 
-// 公開処刑
-func (g *Game) publicExecution(executor, target *Player, fromEmperror bool) {
-	fmt.Printf("公開処刑 ターゲット:%s\n", target.Name())
+// 公開処刑 triggerは発動したカード(少年:1, 皇帝:9)
+func (g *Game) publicExecution(executor, target *Player, trigger int) {
+	g.printf("公開処刑 ターゲット:%s\n", target.Name())
 	if target.Protected() {
-		fmt.Printf("ターゲット:%sは守護下\n", target.Name())
+		g.printf("ターゲット:%sは守護下\n", target.Name())
+		g.emit(Event{Type: EventGuarded, Seat: g.Seat(executor), Target: g.Seat(target)})
 		return
 	}
 	if g.Deck.finished() {
-		fmt.Printf("残り山札なし")
+		g.printf("残り山札なし")
 		return
 	}
 
 	// target
 	next := g.Deck.take()
 	target.Take(next)
-	fmt.Printf("%sの手札: %s\n", target.Name(), target.Hand())
-	// TODO: 引数でPairを渡すか？なるべくゲームルールをここで表現するため、こうしたい
-	discard := executor.SelectOnPublicExecution(target, target.Hand())
+	g.emit(Event{Type: EventDraw, Seat: g.Seat(target), Target: -1})
+	if !g.hotSeat {
+		// 対象の手札は処刑する人だけが見る
+		g.printf("%sの手札: %s\n", target.Name(), target.Hand())
+	}
+	g.pending = Decision{
+		Type:       DecisionPublicExecution,
+		Seat:       g.Seat(executor),
+		Target:     g.Seat(target),
+		Trigger:    trigger,
+		Candidates: append([]int{}, target.Hand().Slice()...),
+	}
+}
+
+func (g *Game) executePublicly(executor, target *Player, discard int, fromEmperror bool) {
 	target.DiscardSpecified(discard)
-	fmt.Printf("捨てるカードを指定:[%d]\n", discard)
+	g.forgetDiscarded(target, discard)
+	g.emit(Event{Type: EventForcedDiscard, Seat: g.Seat(target), Target: g.Seat(executor), Card: discard})
+	g.printf("捨てるカードを指定:[%d]\n", discard)
 
-	if discard == 10 {
+	if discard != 10 {
+		// 残りのカードは全員に公開されている
+		g.reveal(target)
+		return
+	}
 
-		if fromEmperror {
-			fmt.Println("英雄が皇帝に見つかった")
-			fmt.Printf("%s 脱落\n", target.Name())
-			target.Dropout()
-		} else {
-			fmt.Println("英雄が皇帝以外にやられた")
-			ok, c := g.Deck.ReincarnateCard()
-			if ok {
-				fmt.Println("転生")
-				target.Reincarnate(c)
-			} else {
-				fmt.Printf("転生不可 %s 脱落\n", target.Name())
-				target.Dropout()
-			}
-		}
+	if fromEmperror {
+		g.println("英雄が皇帝に見つかった")
+		g.printf("%s 脱落\n", target.Name())
+		g.dropout(target, 9)
+	} else {
+		g.println("英雄が皇帝以外にやられた")
+		g.reincarnate(target)
 	}
 }
 
 // 疫病
 func (g *Game) plague(executor, target *Player) {
-	fmt.Printf("疫病 ターゲット:%s\n", target.Name())
+	g.printf("疫病 ターゲット:%s\n", target.Name())
 	if target.Protected() {
-		fmt.Printf("ターゲット:%sは守護下\n", target.Name())
+		g.printf("ターゲット:%sは守護下\n", target.Name())
+		g.emit(Event{Type: EventGuarded, Seat: g.Seat(executor), Target: g.Seat(target)})
 		return
 	}
 	if g.Deck.finished() {
-		fmt.Printf("残り山札なし")
+		g.printf("残り山札なし")
 		return
 	}
 
 	next := g.Deck.take()
 	target.Take(next)
-	fmt.Println("[?][?]")
-	debugPrintf("%s\n", target.Hand())
-	discard := executor.SelectOnPlague(target, target.Hand())
+	g.emit(Event{Type: EventDraw, Seat: g.Seat(target), Target: -1})
+	g.println("[?][?]")
+	g.debugf("%s\n", target.Hand())
+	g.pending = Decision{
+		Type:       DecisionPlague,
+		Seat:       g.Seat(executor),
+		Target:     g.Seat(target),
+		Trigger:    5,
+		Candidates: append([]int{}, target.Hand().Slice()...),
+	}
+}
+
+func (g *Game) executePlague(executor, target *Player, index int) {
+	discard := target.Hand().At(index)
 	target.DiscardSpecified(discard)
-	fmt.Printf("指定:[%d]\n", discard)
+	g.forgetDiscarded(target, discard)
+	g.emit(Event{Type: EventForcedDiscard, Seat: g.Seat(target), Target: g.Seat(executor), Card: discard})
+	g.printf("指定:[%d]\n", discard)
 
 	if discard == 10 {
 		// 死神・兵士・少年の効果で脱落した場合は、持っている手札を全て捨ててから転生札を引き、ゲームに復帰
-		fmt.Println("英雄がやられた")
-		ok, c := g.Deck.ReincarnateCard()
-		if ok {
-			fmt.Println("転生")
-			target.Reincarnate(c)
-		} else {
-			fmt.Printf("転生不可 %s 脱落\n", target.Name())
-			target.Dropout()
-		}
+		g.println("英雄がやられた")
+		g.reincarnate(target)
+	}
+}
+
+// 転生札があれば転生、なければ脱落
+func (g *Game) reincarnate(target *Player) {
+	g.forgetAbout(target)
+	ok, c := g.Deck.ReincarnateCard()
+	if ok {
+		g.println("転生")
+		target.Reincarnate(c)
+		g.emit(Event{Type: EventReincarnation, Seat: g.Seat(target), Target: -1})
+	} else {
+		g.printf("転生不可 %s 脱落\n", target.Name())
+		g.dropout(target, 10)
 	}
 }
 
+// causeは脱落の原因のカード。最後の比較で負けた場合は0
+func (g *Game) dropout(p *Player, cause int) {
+	p.Dropout()
+	g.emit(Event{Type: EventDropout, Seat: g.Seat(p), Target: -1, Card: cause})
+}
+
 func (g *Game) investigation(executor, target *Player, expect int) {
-	fmt.Printf("%sに対する捜査 %d\n", target.Name(), expect)
+	g.printf("%sに対する捜査 %d\n", target.Name(), expect)
 
 	correct := target.Has(expect)
+	g.emit(Event{Type: EventInvestigation, Seat: g.Seat(executor), Target: g.Seat(target), Expect: expect, Hit: correct})
 	if correct {
-		fmt.Printf("正解 %s脱落\n", target.Name())
-		target.Dropout()
+		g.printf("正解 %s脱落\n", target.Name())
+		g.dropout(target, 2)
 	} else {
-		fmt.Printf("はずれ\n")
+		g.printf("はずれ\n")
+	}
+}
+
+// pがcardを捨てたので、そのカードを知っていたプレイヤーは分からなくなる
+func (g *Game) forgetDiscarded(p *Player, card int) {
+	for _, q := range g.Players {
+		if c, ok := q.known[p.ID()]; ok && c == card {
+			q.forget(p.ID())
+		}
+	}
+}
+
+func (g *Game) forgetAbout(p *Player) {
+	for _, q := range g.Players {
+		q.forget(p.ID())
+	}
+}
+
+// pの手札を全員が知る
+func (g *Game) reveal(p *Player) {
+	c := p.Hand().Get()
+	for _, q := range g.OtherPlayers(p) {
+		q.know(p.ID(), c)
 	}
+	g.emit(Event{Type: EventReveal, Seat: g.Seat(p), Target: -1, Card: c})
+}
+
+// 交換で手札が入れ替わったので、知っている手札も入れ替える
+func (g *Game) exchangeKnowledge(p, target *Player, pc, tc int) {
+	for _, q := range g.Players {
+		if q == p || q == target {
+			continue
+		}
+		kp, okp := q.known[p.ID()]
+		kt, okt := q.known[target.ID()]
+		q.forget(p.ID())
+		q.forget(target.ID())
+		if okp {
+			q.know(target.ID(), kp)
+		}
+		if okt {
+			q.know(p.ID(), kt)
+		}
+	}
+	p.know(target.ID(), pc)
+	target.know(p.ID(), tc)
 }
 
 func (g Game) String() string {
@@ -428,7 +880,32 @@ func (g Game) String() string {
 	return text
 }
 
-func debugPrintf(msg string, args ...interface{}) {
-	m := "--[DEBUG]" + msg
-	fmt.Printf(m, args...)
+func (g *Game) output() io.Writer {
+	if g.out == nil {
+		return os.Stdout
+	}
+	return g.out
+}
+
+// 捨てる出力は書式も作らない。探索のコピーで時間がかかるので
+func (g *Game) printf(format string, args ...interface{}) {
+	if g.out == ioutil.Discard {
+		return
+	}
+	fmt.Fprintf(g.output(), format, args...)
+}
+
+func (g *Game) println(args ...interface{}) {
+	if g.out == ioutil.Discard {
+		return
+	}
+	fmt.Fprintln(g.output(), args...)
+}
+
+// 隠れた情報を出力する。ホットシートでは出さない
+func (g *Game) debugf(msg string, args ...interface{}) {
+	if g.hotSeat {
+		return
+	}
+	g.printf("--[DEBUG]"+msg, args...)
 }
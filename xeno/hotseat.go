@@ -0,0 +1,149 @@
+package xeno
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+const clearScreen = "\033[H\033[2J"
+
+// SetInput sets the source of keys the game waits for in hot-seat mode. nil means os.Stdin.
+func (g *Game) SetInput(r io.Reader) {
+	g.in = r
+	g.line = nil
+}
+
+func (g *Game) input() io.Reader {
+	if g.in == nil {
+		return os.Stdin
+	}
+	return g.in
+}
+
+// HotSeat reports whether private information is shown only to the human deciding
+func (g *Game) HotSeat() bool {
+	return g.hotSeat
+}
+
+// 改行まで読み捨てる。ctxが終わったらそのエラーを返す
+func (g *Game) waitEnter(ctx context.Context) error {
+	g.readLine(ctx)
+	return ctx.Err()
+}
+
+// pに見せたことを読んだEnterを待つ。ゲームが止められたとき、pの制限時間が過ぎたときは待たない
+func (g *Game) waitRead(p *Player) {
+	ctx, cancel := g.waitContext(p)
+	defer cancel()
+	g.waitEnter(ctx)
+}
+
+// 判断でない入力を待つctx。進行中のゲームのctxに、pの制限時間をつける
+func (g *Game) waitContext(p *Player) (context.Context, context.CancelFunc) {
+	ctx := g.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if p.timeout > 0 {
+		return context.WithTimeout(ctx, p.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// 読んだ1行
+type inputLine struct {
+	text string
+	err  error
+}
+
+// ゲームの入力から1行読む。ctxが終わったら待つのをやめてそのエラーを返す。
+// 読みかけの行は捨てずに、次に読むときに受け取る
+func (g *Game) readLine(ctx context.Context) (string, error) {
+	if g.line == nil {
+		line := make(chan inputLine, 1)
+		r := g.input()
+		go func() {
+			text, err := readLine(r)
+			line <- inputLine{text, err}
+		}()
+		g.line = line
+	}
+	select {
+	case l := <-g.line:
+		g.line = nil
+		return l.text, l.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// 1行読む。改行は含まない。
+// 続けて読む人が読み残しを受け取れるように、1バイトずつ読む
+func readLine(r io.Reader) (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := r.Read(b)
+		if n == 1 {
+			if b[0] == '\n' {
+				return string(line), nil
+			}
+			line = append(line, b[0])
+		}
+		if err != nil {
+			return string(line), err
+		}
+	}
+}
+
+// 画面を消して、pに渡すのを待ってからpだけが知っていることを表示する。ctxが終わったらそのエラーを返す
+func (g *Game) passTo(ctx context.Context, p *Player) error {
+	g.printf(clearScreen)
+	g.printf("%s に渡してください (Enter)\n", p.Name())
+	if err := g.waitEnter(ctx); err != nil {
+		return err
+	}
+
+	v := g.View(p)
+	g.printf("%s の手札: %v\n", p.Name(), v.Hand)
+	var seats []int
+	for s := range v.Known {
+		seats = append(seats, s)
+	}
+	sort.Ints(seats)
+	for _, s := range seats {
+		g.printf("%s の手札を知っている: [%d]\n", g.Players[s].Name(), v.Known[s])
+	}
+	return nil
+}
+
+// 人間だけが知るべきことを見せる。ホットシートでなければそのまま
+func (g *Game) privately(p *Player, fn func()) {
+	if !g.hotSeat || !isHuman(p) {
+		fn()
+		return
+	}
+	ctx, cancel := g.waitContext(p)
+	defer cancel()
+	g.passTo(ctx, p)
+	fn()
+	g.printf(clearScreen)
+}
+
+// 全員に見えている状態。ホットシートでGame.Stringの代わりに使う
+func (g *Game) publicString() string {
+	text := fmt.Sprintf("----- ターン%d ------------------------\n", g.turn)
+	text += fmt.Sprintf("= 残り: %d枚\n", g.Deck.count())
+	for _, p := range g.Players {
+		alive := ""
+		if p.dropped {
+			alive = "(脱落)"
+		}
+		text += fmt.Sprintf("= %s %s: 手札%d枚 捨てたカード:%v\n", p.name, alive, p.hand.Count(), p.discarded)
+	}
+	text += "--------------------------------------\n"
+	return text
+}
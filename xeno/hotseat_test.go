@@ -0,0 +1,75 @@
+package xeno
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// 0から10までを繰り返す入力。どの判断もいずれ合法な答えになる
+func manualInput() *strings.Reader {
+	var cycle string
+	for i := 0; i <= 10; i++ {
+		cycle += fmt.Sprintf("%d\n", i)
+	}
+	return strings.NewReader(strings.Repeat(cycle, 1000))
+}
+
+func TestGame_HotSeat(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		g := NewGame(GameConfig{Players: []PlayerConfig{{Name: "A", Manual: true}, {Name: "B", Manual: true}, {Name: "C"}}, HotSeat: true})
+		var out bytes.Buffer
+		g.SetOutput(&out)
+		g.SetInput(manualInput())
+		g.Loop()
+
+		text := out.String()
+		if !strings.Contains(text, "Select ") {
+			t.Fatalf("humans are not asked:\n%s", text)
+		}
+		for _, leak := range []string{"= 山札:", "--[DEBUG]", "転生札"} {
+			if strings.Contains(text, leak) {
+				t.Fatalf("hidden information %q is shown:\n%s", leak, text)
+			}
+		}
+		// 手札は渡された人のものだけ。最後の比較で全員に見せるのは除く
+		if end := strings.Index(text, "ゲーム終了"); end >= 0 {
+			text = text[:end]
+		}
+		screens := strings.Split(text, clearScreen)
+		passed := 0
+		for _, screen := range screens {
+			if !strings.Contains(screen, "に渡してください") {
+				if strings.Contains(screen, "手札: ") {
+					t.Errorf("hand is shown without passing:\n%s", screen)
+				}
+				continue
+			}
+			passed++
+			name := screen[:strings.Index(screen, " ")]
+			for _, line := range strings.Split(screen, "\n") {
+				if strings.Contains(line, " の手札: ") && !strings.HasPrefix(line, name+" ") {
+					t.Errorf("%s sees %s", name, line)
+				}
+			}
+			if strings.Contains(screen, "C に渡して") {
+				t.Errorf("passed to a computer")
+			}
+		}
+		if passed == 0 {
+			t.Errorf("never passed")
+		}
+	}
+
+	g := NewGame(GameConfig{Players: []PlayerConfig{{}, {}}})
+	var out bytes.Buffer
+	g.SetOutput(&out)
+	for _, p := range g.Players {
+		p.strategy = RandomStrategy{}
+	}
+	g.Loop()
+	if g.HotSeat() || !strings.Contains(out.String(), "= 山札:") || strings.Contains(out.String(), clearScreen) {
+		t.Errorf("normal mode should show everything")
+	}
+}
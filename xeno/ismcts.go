@@ -0,0 +1,204 @@
+package xeno
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// ISMCTSConfig は ISMCTSStrategy の探索量の設定
+type ISMCTSConfig struct {
+	Iterations  int           // 1回の判断あたりの探索回数。0ならTimeLimitまで
+	TimeLimit   time.Duration // 1回の判断あたりの探索時間。0なら無制限
+	Exploration float64       // UCBの探索係数。0ならデフォルト
+	Seed        int64
+}
+
+const (
+	defaultISMCTSIterations  = 1000
+	defaultISMCTSExploration = 0.7
+)
+
+// ISMCTSStrategy は Information Set Monte Carlo Tree Search (single observer) で判断する。
+// 判断のたびに自分の視点から隠れた情報を決定化し、ランダムプレイアウトで勝率を見積もる。
+type ISMCTSStrategy struct {
+	conf ISMCTSConfig
+	rng  *rand.Rand
+	game *Game // 直近に通知されたゲーム。公開処刑・疫病の判断で使う
+}
+
+func NewISMCTSStrategy(conf ISMCTSConfig) *ISMCTSStrategy {
+	if conf.Iterations <= 0 && conf.TimeLimit <= 0 {
+		conf.Iterations = defaultISMCTSIterations
+	}
+	if conf.Exploration <= 0 {
+		conf.Exploration = defaultISMCTSExploration
+	}
+	return &ISMCTSStrategy{
+		conf: conf,
+		rng:  rand.New(rand.NewSource(conf.Seed)),
+	}
+}
+
+func (s *ISMCTSStrategy) Clone() PlayerStrategy {
+	conf := s.conf
+	conf.Seed = s.rng.Int63()
+	c := NewISMCTSStrategy(conf)
+	c.game = s.game
+	return c
+}
+
+// Decide searches until the limits or ctx is done
+func (s *ISMCTSStrategy) Decide(ctx context.Context, g *Game, d Decision) Action {
+	s.game = g
+	return s.SearchContext(ctx, g.view(g.Players[d.Seat], d))
+}
+
+func (s *ISMCTSStrategy) SelectDiscard(g *Game, p *Player) CardEvent {
+	s.game = g
+	return g.actionEvent(s.Search(g.discardView(p)))
+}
+
+func (s *ISMCTSStrategy) SelectFromWise(g *Game, candidates []int) int {
+	s.game = g
+	return s.Search(g.View(g.Players[g.pending.Seat])).Card()
+}
+
+func (s *ISMCTSStrategy) SelectOnPublicExecution(player, target *Player, hand Hand) int {
+	return s.Search(s.game.View(player)).Card()
+}
+
+func (s *ISMCTSStrategy) SelectOnPlague(player, target *Player, hand Hand) int {
+	return hand.At(s.Search(s.game.View(player)).Index())
+}
+
+func (s *ISMCTSStrategy) KnowByClairvoyance(g *Game, player, target *Player, c int) {
+	s.game = g
+}
+
+func (s *ISMCTSStrategy) OnOpponentEvent(g *Game, player, opponent *Player, e CardEvent) {
+	s.game = g
+}
+
+type mctsNode struct {
+	seat     int // このノードへの判断をしたプレイヤー
+	action   Action
+	parent   *mctsNode
+	children []*mctsNode
+	visits   int
+	avail    int
+	reward   float64
+}
+
+func (n *mctsNode) child(seat int, a Action) *mctsNode {
+	for _, c := range n.children {
+		if c.seat == seat && c.action == a {
+			return c
+		}
+	}
+	return nil
+}
+
+func (n *mctsNode) ucb(exploration float64) float64 {
+	return n.reward/float64(n.visits) + exploration*math.Sqrt(math.Log(float64(n.avail))/float64(n.visits))
+}
+
+// Search returns the most visited action for the decision of v
+func (s *ISMCTSStrategy) Search(v PlayerView) Action {
+	return s.SearchContext(context.Background(), v)
+}
+
+// SearchContext is Search which stops searching when ctx is done
+func (s *ISMCTSStrategy) SearchContext(ctx context.Context, v PlayerView) Action {
+	actions := LegalActions(v)
+	if len(actions) == 1 {
+		return actions[0]
+	}
+
+	root := &mctsNode{seat: -1}
+	start := time.Now()
+	for i := 0; ; i++ {
+		if s.conf.Iterations > 0 && i >= s.conf.Iterations {
+			break
+		}
+		if s.conf.TimeLimit > 0 && time.Since(start) >= s.conf.TimeLimit {
+			break
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		g, err := Determinize(v, s.rng)
+		if err != nil {
+			// 配り直せない視点では読めないので、最初の手にする
+			break
+		}
+		s.iterate(ctx, root, g)
+	}
+
+	best := actions[0]
+	most := -1
+	for _, c := range root.children {
+		if c.visits > most {
+			best = c.action
+			most = c.visits
+		}
+	}
+	return best
+}
+
+// ctxが終わったら途中の結果は捨てる
+func (s *ISMCTSStrategy) iterate(ctx context.Context, root *mctsNode, g *Game) {
+	node := root
+	// Selection & Expansion
+	for !g.Over() {
+		if ctx.Err() != nil {
+			return
+		}
+		d := g.Pending()
+		legal := LegalActions(g.View(g.Players[d.Seat]))
+
+		var untried []Action
+		var best *mctsNode
+		for _, a := range legal {
+			c := node.child(d.Seat, a)
+			if c == nil {
+				untried = append(untried, a)
+				continue
+			}
+			c.avail++
+			if best == nil || c.ucb(s.conf.Exploration) > best.ucb(s.conf.Exploration) {
+				best = c
+			}
+		}
+
+		if len(untried) > 0 {
+			a := untried[s.rng.Intn(len(untried))]
+			c := &mctsNode{seat: d.Seat, action: a, parent: node, avail: 1}
+			node.children = append(node.children, c)
+			g.Step(a)
+			node = c
+			break
+		}
+		g.Step(best.action)
+		node = best
+	}
+
+	// Simulation
+	for !g.Over() {
+		if ctx.Err() != nil {
+			return
+		}
+		d := g.Pending()
+		legal := LegalActions(g.View(g.Players[d.Seat]))
+		g.Step(legal[s.rng.Intn(len(legal))])
+	}
+
+	// Backpropagation
+	for ; node != nil; node = node.parent {
+		node.visits++
+		if node.seat >= 0 && !g.Players[node.seat].Dropped() {
+			node.reward++
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package xeno
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestISMCTSStrategy_Loop(t *testing.T) {
+	// 全ての判断で合法手を返せばLoopは最後まで進む
+	for i := 0; i < 10; i++ {
+		g := newQuietGame(2 + i%3)
+		for j, p := range g.Players {
+			p.strategy = NewISMCTSStrategy(ISMCTSConfig{Iterations: 30, Seed: int64(i*10 + j)})
+		}
+		g.Loop()
+		if !g.Over() {
+			t.Errorf("game should be over")
+		}
+	}
+}
+
+func TestISMCTSStrategy_BeatsCommStrategy(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping head-to-head games in short mode")
+	}
+
+	games := 60
+	wins := 0
+	for i := 0; i < games; i++ {
+		// 配り方と相手の手は乱数の種で決まる
+		rng := rand.New(rand.NewSource(int64(i)))
+		g := newSeededGame(2, rng)
+		seat := i % 2
+		g.Players[1-seat].strategy = NewCommStrategy(rng)
+		g.Players[seat].strategy = NewISMCTSStrategy(ISMCTSConfig{Iterations: 200, Seed: int64(i)})
+		g.Loop()
+		if !g.Players[seat].Dropped() {
+			wins++
+		}
+	}
+	if wins*2 <= games {
+		t.Errorf("ISMCTSStrategy won only %d/%d games against CommStrategy", wins, games)
+	}
+}
@@ -0,0 +1,322 @@
+package xeno
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 記法
+//
+// 1手は "席 判断" で書く。席は P1, P2, ... (席+1)。
+//
+//	P1 d4            4を捨てる (対象なし)
+//	P1 d5>P2         5を捨ててP2を対象にする
+//	P2 d2>P1?7       2を捨ててP1の手札を7と予想する
+//	P1 wise[3,6,9]→6 賢者の3枚から6を取る
+//	P1 exec>P2[3,10]→10 公開処刑でP2の3と10から10を捨てさせる
+//	P1 plague>P2→1   疫病でP2の右 (0:左, 1:右) のカードを捨てさせる
+//
+// → は -> とも書ける。[...] と >P は省略でき、書いてあればゲームと照らし合わせる。
+//
+// 1ゲームは見出しと1行1手で書く。# から行末まではコメント。
+//
+//	players: Alice, Bob
+//	deck: 3 5 1 ...   配る前の山札。最後が転生札
+//	shuffle: 4 2 ...  賢者の後にシャッフルした山札 (順に何行でも)
+//	P1 d5>P2
+//	...
+
+const arrow = "→"
+
+func formatSeat(seat int) string {
+	return fmt.Sprintf("P%d", seat+1)
+}
+
+func parseSeat(s string) (int, error) {
+	if !strings.HasPrefix(s, "P") {
+		return 0, fmt.Errorf("invalid seat: %q", s)
+	}
+	n, err := strconv.Atoi(s[1:])
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid seat: %q", s)
+	}
+	return n - 1, nil
+}
+
+func formatCards(cards []int) string {
+	s := make([]string, len(cards))
+	for i, c := range cards {
+		s[i] = strconv.Itoa(c)
+	}
+	return "[" + strings.Join(s, ",") + "]"
+}
+
+// FormatMove writes a, the answer to d, in the notation
+func FormatMove(d Decision, a Action) string {
+	s := formatSeat(d.Seat) + " "
+	switch a.Type() {
+	case DecisionDiscard:
+		s += fmt.Sprintf("d%d", a.Card())
+		if a.Target() >= 0 {
+			s += ">" + formatSeat(a.Target())
+		}
+		if a.Expect() > 0 {
+			s += fmt.Sprintf("?%d", a.Expect())
+		}
+	case DecisionWise:
+		s += fmt.Sprintf("wise%s%s%d", formatCards(d.Candidates), arrow, a.Card())
+	case DecisionPublicExecution:
+		s += fmt.Sprintf("exec>%s%s%s%d", formatSeat(d.Target), formatCards(d.Candidates), arrow, a.Card())
+	case DecisionPlague:
+		s += fmt.Sprintf("plague>%s%s%d", formatSeat(d.Target), arrow, a.Index())
+	default:
+		s += "-"
+	}
+	return s
+}
+
+// ParseMove parses a move written by FormatMove.
+// The returned decision has the seat, and the target and candidates if written. Target is -1 if not written.
+func ParseMove(s string) (Decision, Action, error) {
+	invalid := fmt.Errorf("invalid move: %q", s)
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return Decision{}, 0, invalid
+	}
+	seat, err := parseSeat(fields[0])
+	if err != nil {
+		return Decision{}, 0, err
+	}
+	d := Decision{Seat: seat, Target: -1}
+	body := strings.Replace(fields[1], "->", arrow, 1)
+	num := func(t string) (int, bool) {
+		n, err := strconv.Atoi(t)
+		return n, err == nil && n >= 0 && n <= 10
+	}
+
+	if strings.HasPrefix(body, "d") {
+		rest, expect, target := body[1:], 0, -1
+		if i := strings.Index(rest, "?"); i >= 0 {
+			e, ok := num(rest[i+1:])
+			if !ok {
+				return Decision{}, 0, invalid
+			}
+			rest, expect = rest[:i], e
+		}
+		if i := strings.Index(rest, ">"); i >= 0 {
+			t, err := parseSeat(rest[i+1:])
+			if err != nil {
+				return Decision{}, 0, invalid
+			}
+			rest, target = rest[:i], t
+		}
+		card, ok := num(rest)
+		if !ok {
+			return Decision{}, 0, invalid
+		}
+		d.Type = DecisionDiscard
+		return d, NewDiscardAction(card, target, expect), nil
+	}
+
+	// wise / exec / plague: 名前 [>P席] [[カード,...]] → 数
+	i := strings.Index(body, arrow)
+	if i < 0 {
+		return Decision{}, 0, invalid
+	}
+	head, value := body[:i], body[i+len(arrow):]
+	n, ok := num(value)
+	if !ok {
+		return Decision{}, 0, invalid
+	}
+	if j := strings.Index(head, "["); j >= 0 {
+		if !strings.HasSuffix(head, "]") {
+			return Decision{}, 0, invalid
+		}
+		for _, c := range strings.Split(head[j+1:len(head)-1], ",") {
+			card, ok := num(c)
+			if !ok {
+				return Decision{}, 0, invalid
+			}
+			d.Candidates = append(d.Candidates, card)
+		}
+		head = head[:j]
+	}
+	if j := strings.Index(head, ">"); j >= 0 {
+		t, err := parseSeat(head[j+1:])
+		if err != nil {
+			return Decision{}, 0, invalid
+		}
+		head, d.Target = head[:j], t
+	}
+	switch head {
+	case "wise":
+		d.Type = DecisionWise
+		if d.Target >= 0 {
+			return Decision{}, 0, invalid
+		}
+		return d, NewWiseAction(n), nil
+	case "exec":
+		d.Type = DecisionPublicExecution
+		return d, NewPublicExecutionAction(n), nil
+	case "plague":
+		d.Type = DecisionPlague
+		if d.Candidates != nil || n > 1 {
+			return Decision{}, 0, invalid
+		}
+		return d, NewPlagueAction(n), nil
+	}
+	return Decision{}, 0, invalid
+}
+
+// 書かれた判断が判断待ちと合っているか
+func checkMove(pending, written Decision) error {
+	if pending.Type == DecisionNone {
+		return fmt.Errorf("no pending decision")
+	}
+	if written.Seat != pending.Seat {
+		return fmt.Errorf("%s is deciding, not %s", formatSeat(pending.Seat), formatSeat(written.Seat))
+	}
+	if written.Type != pending.Type {
+		return fmt.Errorf("pending decision is %v, not %v", pending.Type, written.Type)
+	}
+	if written.Target >= 0 && written.Target != pending.Target {
+		return fmt.Errorf("target is %s, not %s", formatSeat(pending.Target), formatSeat(written.Target))
+	}
+	if written.Candidates != nil && formatCards(written.Candidates) != formatCards(pending.Candidates) {
+		return fmt.Errorf("candidates are %v, not %v", pending.Candidates, written.Candidates)
+	}
+	return nil
+}
+
+// Play steps moves written one per line in the notation. Call it after Start.
+func (g *Game) Play(moves string) error {
+	return scanLines(moves, func(n int, line string) error {
+		d, a, err := ParseMove(line)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", n, err)
+		}
+		if err := checkMove(g.pending, d); err != nil {
+			return fmt.Errorf("line %d: %v", n, err)
+		}
+		if err := g.Step(a); err != nil {
+			return fmt.Errorf("line %d: %v", n, err)
+		}
+		return nil
+	})
+}
+
+// コメントと空行を除いた行ごとにfnを呼ぶ
+func scanLines(text string, fn func(n int, line string) error) error {
+	sc := bufio.NewScanner(strings.NewReader(text))
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if err := fn(n, line); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+// FormatRecord writes r in the notation. r is replayed to write the candidates.
+func FormatRecord(r *Record) (string, error) {
+	var b strings.Builder
+	for _, name := range r.Players {
+		if strings.ContainsAny(name, ",#\n") {
+			return "", fmt.Errorf("player name %q cannot be written", name)
+		}
+	}
+	fmt.Fprintf(&b, "players: %s\n", strings.Join(r.Players, ", "))
+	fmt.Fprintf(&b, "deck: %s\n", joinInts(r.Deck))
+	for _, s := range r.Shuffles {
+		fmt.Fprintf(&b, "shuffle: %s\n", joinInts(s))
+	}
+	_, err := r.Replay(func(g *Game, m Move) {
+		fmt.Fprintln(&b, FormatMove(g.Pending(), m.Action))
+	})
+	if err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// ParseRecord parses a game written by FormatRecord and checks it by replaying
+func ParseRecord(text string) (*Record, error) {
+	r := &Record{Version: RecordVersion, Moves: []Move{}}
+	var written []Decision
+	err := scanLines(text, func(n int, line string) error {
+		var err error
+		switch {
+		case strings.HasPrefix(line, "players:"):
+			for _, name := range strings.Split(line[len("players:"):], ",") {
+				r.Players = append(r.Players, strings.TrimSpace(name))
+			}
+		case strings.HasPrefix(line, "deck:"):
+			r.Deck, err = parseInts(line[len("deck:"):])
+		case strings.HasPrefix(line, "shuffle:"):
+			var s []int
+			s, err = parseInts(line[len("shuffle:"):])
+			r.Shuffles = append(r.Shuffles, s)
+		default:
+			var d Decision
+			var a Action
+			d, a, err = ParseMove(line)
+			r.Moves = append(r.Moves, Move{Seat: d.Seat, Action: a})
+			written = append(written, d)
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %v", n, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(r.Players) < 2 {
+		return nil, fmt.Errorf("players are not written")
+	}
+
+	i := 0
+	var mismatch error
+	_, err = r.Replay(func(g *Game, m Move) {
+		if err := checkMove(g.Pending(), written[i]); err != nil && mismatch == nil {
+			mismatch = fmt.Errorf("move %d: %v", i, err)
+		}
+		i++
+	})
+	if err != nil {
+		return nil, err
+	}
+	if mismatch != nil {
+		return nil, mismatch
+	}
+	return r, nil
+}
+
+func joinInts(ns []int) string {
+	s := make([]string, len(ns))
+	for i, n := range ns {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, " ")
+}
+
+func parseInts(s string) ([]int, error) {
+	ns := []int{}
+	for _, f := range strings.Fields(s) {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, err
+		}
+		ns = append(ns, n)
+	}
+	return ns, nil
+}
@@ -0,0 +1,114 @@
+package xeno
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormatMove(t *testing.T) {
+	tests := []struct {
+		d    Decision
+		a    Action
+		want string
+	}{
+		{Decision{Seat: 0}, NewDiscardAction(4, -1, 0), "P1 d4"},
+		{Decision{Seat: 0}, NewDiscardAction(5, 1, 0), "P1 d5>P2"},
+		{Decision{Seat: 1}, NewDiscardAction(2, 0, 7), "P2 d2>P1?7"},
+		{Decision{Seat: 0, Candidates: []int{3, 6, 9}}, NewWiseAction(6), "P1 wise[3,6,9]→6"},
+		{Decision{Seat: 0, Target: 1, Candidates: []int{3, 10}}, NewPublicExecutionAction(10), "P1 exec>P2[3,10]→10"},
+		{Decision{Seat: 2, Target: 0}, NewPlagueAction(1), "P3 plague>P1→1"},
+	}
+	for _, tt := range tests {
+		tt.d.Type = tt.a.Type()
+		if got := FormatMove(tt.d, tt.a); got != tt.want {
+			t.Errorf("want: %s, got: %s", tt.want, got)
+		}
+		d, a, err := ParseMove(tt.want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a != tt.a || d.Seat != tt.d.Seat || d.Type != tt.d.Type || !reflect.DeepEqual(d.Candidates, tt.d.Candidates) {
+			t.Errorf("%s: want: %v %v, got: %v %v", tt.want, tt.d, tt.a, d, a)
+		}
+	}
+}
+
+func TestParseMove(t *testing.T) {
+	d, a, err := ParseMove("P1 wise->6")
+	if err != nil || a != NewWiseAction(6) || d.Candidates != nil || d.Target != -1 {
+		t.Errorf("got: %v %v %v", d, a, err)
+	}
+	for _, s := range []string{"", "P1", "d5", "P0 d5", "P1 d5>2", "P1 d11", "P1 wise[3,x]→3", "P1 wise>P2→3", "P1 plague→2", "P1 plague[1,2]→1", "P1 jump→1", "P1 exec→"} {
+		if _, _, err := ParseMove(s); err == nil {
+			t.Errorf("%q: want: error", s)
+		}
+	}
+}
+
+func TestFormatRecord(t *testing.T) {
+	wises := 0
+	Simulate(SimulationConfig{Games: 30, Players: 3, Seed: 2, Strategy: func(seat int, rng *rand.Rand) PlayerStrategy {
+		return NewExpertStrategy(ExpertConfig{Seed: rng.Int63()})
+	}}, func(i int, r *Record, g *Game) {
+		text, err := FormatRecord(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wises += strings.Count(text, "wise[")
+		parsed, err := ParseRecord(text)
+		if err != nil {
+			t.Fatalf("%v\n%s", err, text)
+		}
+		if !reflect.DeepEqual(r, parsed) {
+			t.Fatalf("game %d: want: %v, got: %v\n%s", i, r, parsed, text)
+		}
+	})
+	if wises == 0 {
+		t.Errorf("no game used the sage")
+	}
+}
+
+func TestGame_Play(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	g := newSeededGame(2, rng)
+	r := g.StartRecording()
+	g.Loop()
+	text, err := FormatRecord(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var moves []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, "P") {
+			moves = append(moves, line)
+		}
+	}
+
+	// 最初の状態から記法の手を打つ
+	fresh := *r
+	fresh.Moves = nil
+	played, err := fresh.Replay(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := played.Play("# 再現\n" + strings.Join(moves, "\n")); err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range g.Players {
+		if !reflect.DeepEqual(p.Discarded(), played.Players[i].Discarded()) {
+			t.Errorf("seat %d: want: %v, got: %v", i, p, played.Players[i])
+		}
+	}
+
+	other, _ := fresh.Replay(nil)
+	wrong := strings.Replace(moves[0], "P"+moves[0][1:2], "P9", 1)
+	if err := other.Play(wrong); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("want: error at line 1, got: %v", err)
+	}
+
+	if _, err := ParseRecord(strings.Replace(text, "deck:", "deck: 1", 1)); err == nil {
+		t.Errorf("want: error for an invalid deck")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"math/rand"
+	"time"
 )
 
 type Hand struct {
@@ -116,6 +117,16 @@ func (h Hand) String() string {
 type PlayerConfig struct {
 	Name   string
 	Manual bool
+	// StrategyName は登録された戦略の指定 (例: "expert:aggressive")。Manualより優先する
+	StrategyName string
+	// Strategy は直接指定する戦略。StrategyNameより優先する
+	Strategy PlayerStrategy
+	// Advisor は人間 (Manual) に確率と手の提案を表示する
+	Advisor bool
+	// Timeout は1回の判断の制限時間。0なら GameConfig.DecisionTimeout。ContextStrategy にだけ効く
+	Timeout time.Duration
+	// Profile は通算成績を記録するキー。空なら記録しない
+	Profile string
 }
 
 // PlayerStrategyによりコンピュータや人間などにより判断する部分をPlayerから移譲
@@ -128,6 +139,33 @@ type PlayerStrategy interface {
 	OnOpponentEvent(g *Game, player, opponent *Player, e CardEvent)
 }
 
+// StrategyCloner is implemented by strategies which have their own state to copy on Game.Clone
+type StrategyCloner interface {
+	Clone() PlayerStrategy
+}
+
+// 他の戦略を包んで判断の一部を任せる戦略
+type strategyWrapper interface {
+	inner() PlayerStrategy
+}
+
+// strategySeat returns the seat whose strategy is s or wraps s, or -1
+func strategySeat(g *Game, s PlayerStrategy) int {
+	for i, p := range g.Players {
+		for ps := p.strategy; ps != nil; {
+			if ps == s {
+				return i
+			}
+			w, ok := ps.(strategyWrapper)
+			if !ok {
+				break
+			}
+			ps = w.inner()
+		}
+	}
+	return -1
+}
+
 type PlayerID int
 
 type Player struct {
@@ -139,14 +177,26 @@ type Player struct {
 	calledWise bool
 	dropped    bool
 	manual     bool
-	strategy   PlayerStrategy // 戦略
+	strategy   PlayerStrategy   // 戦略
+	timeout    time.Duration    // 1回の判断の制限時間
+	known      map[PlayerID]int // 透視・交換・公開処刑で知った相手の手札
 }
 
 var (
 	playerCount = 0
 )
 
+// NewPlayer panics if conf.StrategyName can't build a strategy. BuildPlayer returns the error instead.
 func NewPlayer(conf PlayerConfig) *Player {
+	p, err := BuildPlayer(conf)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// BuildPlayer returns an error if conf.StrategyName can't build a strategy
+func BuildPlayer(conf PlayerConfig) (*Player, error) {
 	playerCount++
 	id := PlayerID(playerCount)
 	name := conf.Name
@@ -154,9 +204,17 @@ func NewPlayer(conf PlayerConfig) *Player {
 		name = fmt.Sprintf("プレイヤー%d", id)
 	}
 	var s PlayerStrategy
-	if conf.Manual {
-		s = ManualStrategy{}
-	} else {
+	switch {
+	case conf.Strategy != nil:
+		s = conf.Strategy
+	case conf.StrategyName != "":
+		var err error
+		if s, err = NewStrategy(conf.StrategyName); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+	case conf.Manual:
+		s = NewManualStrategy(conf.Advisor)
+	default:
 		s = CommStrategy{opponentInfo: map[PlayerID]int{}}
 	}
 	return &Player{
@@ -165,7 +223,8 @@ func NewPlayer(conf PlayerConfig) *Player {
 		hand:     Hand{cards: []int{}},
 		manual:   conf.Manual,
 		strategy: s,
-	}
+		timeout:  conf.Timeout,
+	}, nil
 }
 func (p *Player) ID() PlayerID {
 	return p.id
@@ -195,6 +254,14 @@ func (p *Player) Protected() bool {
 	return p.protected
 }
 
+func (p *Player) Strategy() PlayerStrategy {
+	return p.strategy
+}
+
+func (p *Player) SetStrategy(s PlayerStrategy) {
+	p.strategy = s
+}
+
 func (p *Player) Take(next int) {
 	p.hand.Add(next)
 }
@@ -211,17 +278,31 @@ func (p *Player) Discarded() []int {
 	return append([]int{}, p.discarded...)
 }
 
+func (p *Player) SelectDiscard(g *Game) CardEvent {
+	return p.strategy.SelectDiscard(g, p)
+}
+
+func (p *Player) SelectFromWise(g *Game, candidates []int) int {
+	return p.strategy.SelectFromWise(g, candidates)
+}
+
+// Discard asks the strategy and discards the card.
+//
+// Deprecated: the game asks strategies by itself. Use SelectDiscard to ask without discarding.
 func (p *Player) Discard(g *Game) CardEvent {
 	if p.hand.Count() < 2 {
 		return CardEvent{}
 	}
-	e := p.strategy.SelectDiscard(g, p)
+	e := p.SelectDiscard(g)
 	p.DiscardSpecified(e.Card)
 	return e
 }
 
+// TakeFromWise asks the strategy, takes the selected card and returns the others.
+//
+// Deprecated: the game asks strategies by itself. Use SelectFromWise to ask without taking.
 func (p *Player) TakeFromWise(g *Game, candidates []int) (remains []int) {
-	selected := p.strategy.SelectFromWise(g, candidates)
+	selected := p.SelectFromWise(g, candidates)
 	found := false
 	for _, c := range candidates {
 		if c == selected && !found {
@@ -286,6 +367,39 @@ func (p *Player) OnOpponentEvent(g *Game, opponent *Player, e CardEvent) {
 	p.strategy.OnOpponentEvent(g, p, opponent, e)
 }
 
+func (p *Player) know(id PlayerID, c int) {
+	if p.known == nil {
+		p.known = map[PlayerID]int{}
+	}
+	p.known[id] = c
+}
+
+func (p *Player) forget(id PlayerID) {
+	delete(p.known, id)
+}
+
+// Known returns opponents' cards p knows
+func (p *Player) Known() map[PlayerID]int {
+	known := make(map[PlayerID]int, len(p.known))
+	for id, c := range p.known {
+		known[id] = c
+	}
+	return known
+}
+
+func (p *Player) clone() *Player {
+	c := *p
+	c.hand = Hand{cards: append([]int{}, p.hand.cards...)}
+	c.discarded = append([]int(nil), p.discarded...)
+	if p.known != nil {
+		c.known = p.Known()
+	}
+	if sc, ok := p.strategy.(StrategyCloner); ok {
+		c.strategy = sc.Clone()
+	}
+	return &c
+}
+
 func (p Player) String() string {
 	alive := ""
 	if p.dropped {
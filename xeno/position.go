@@ -0,0 +1,355 @@
+package xeno
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 局面の記法
+//
+// 1行に1項目を書く。# から行末まではコメント。? は分からないカードで、残りのカードから乱数で配る。
+//
+//	players: Alice, Bob     名前 (省略するとP1, P2, ...)
+//	turn: 4                 ターン。手番は turn % 人数 の席 (省略すると0)
+//	boy: yes                少年が1枚目を捨て済みか (省略するとno)
+//	deck: ? ? 3             山札。先頭から引く (省略すると残りのカード全て)
+//	reincarnation: ?        転生札。- なら使用済み (省略すると?)
+//	P1: hand 6 9; discarded 4 2; protected; wise; known P2=5
+//	P2: hand ?; discarded 7
+//	P3: discarded 5 1; dropped
+//
+// 手番の人が手札を2枚持っていれば捨てる判断から、1枚なら山札を引くところから始める。
+// deck を書いたときに余ったカードは、全部で18枚になるようにP1から順に捨てたカードに加える。
+
+type positionPlayer struct {
+	hand, discarded []int
+	protected, wise bool
+	dropped         bool
+	known           map[int]int // 席→カード
+}
+
+// ParsePosition builds a game from a position. Unknown cards are dealt by rng (nil means math/rand).
+// The returned game waits for the decision of the player to move, like after Start, and its output is discarded.
+func ParsePosition(text string, rng *rand.Rand) (*Game, error) {
+	var names []string
+	var players []*positionPlayer
+	var deck []int
+	deckGiven := false
+	reinc := []int{0} // 0は?
+	turn := 0
+	boy := false
+
+	err := scanLines(text, func(n int, line string) error {
+		i := strings.Index(line, ":")
+		if i < 0 {
+			return fmt.Errorf("line %d: invalid line: %q", n, line)
+		}
+		key, value := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		var err error
+		switch key {
+		case "players":
+			for _, name := range strings.Split(value, ",") {
+				names = append(names, strings.TrimSpace(name))
+			}
+		case "turn":
+			turn, err = strconv.Atoi(value)
+		case "boy":
+			boy, err = parseYesNo(value)
+		case "deck":
+			deckGiven = true
+			deck, err = parsePositionCards(value)
+		case "reincarnation":
+			if value == "-" {
+				reinc = nil
+			} else {
+				reinc, err = parsePositionCards(value)
+				if err == nil && len(reinc) != 1 {
+					err = fmt.Errorf("one card is required")
+				}
+			}
+		default:
+			var seat int
+			if seat, err = parseSeat(key); err != nil {
+				break
+			}
+			if seat != len(players) {
+				err = fmt.Errorf("%s is out of order", key)
+				break
+			}
+			var p *positionPlayer
+			p, err = parsePositionPlayer(value)
+			players = append(players, p)
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %v", n, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(players) < 2 {
+		return nil, fmt.Errorf("at least 2 players are required")
+	}
+	if names != nil && len(names) != len(players) {
+		return nil, fmt.Errorf("%d names for %d players", len(names), len(players))
+	}
+	current := turn % len(players)
+	for i, p := range players {
+		switch {
+		case p.dropped && len(p.hand) > 0:
+			return nil, fmt.Errorf("%s is dropped but has cards", formatSeat(i))
+		case !p.dropped && i == current && (len(p.hand) < 1 || len(p.hand) > 2):
+			return nil, fmt.Errorf("%s to move must have 1 or 2 cards", formatSeat(i))
+		case !p.dropped && i != current && len(p.hand) != 1:
+			return nil, fmt.Errorf("%s must have 1 card", formatSeat(i))
+		}
+		for s := range p.known {
+			if s < 0 || s >= len(players) || s == i {
+				return nil, fmt.Errorf("%s knows an invalid seat", formatSeat(i))
+			}
+		}
+	}
+
+	// 使われていないカードで?を埋める
+	rest := map[int]int{}
+	for _, c := range AllCards {
+		rest[c]++
+	}
+	use := func(cards []int) {
+		for _, c := range cards {
+			if c != 0 {
+				rest[c]--
+			}
+		}
+	}
+	for _, p := range players {
+		use(p.hand)
+		use(p.discarded)
+	}
+	use(deck)
+	use(reinc)
+	var pool []int
+	for c := 1; c <= 10; c++ {
+		if rest[c] < 0 {
+			return nil, fmt.Errorf("too many [%d]", c)
+		}
+		for i := 0; i < rest[c]; i++ {
+			pool = append(pool, c)
+		}
+	}
+	if rng == nil {
+		pool = RandomShuffler{}.Shuffle(pool)
+	} else {
+		pool = rngShuffler{rng: rng}.Shuffle(pool)
+	}
+	fill := func(cards []int) error {
+		for i, c := range cards {
+			if c != 0 {
+				continue
+			}
+			if len(pool) == 0 {
+				return fmt.Errorf("too many cards")
+			}
+			cards[i], pool = pool[0], pool[1:]
+		}
+		return nil
+	}
+	for _, p := range players {
+		if err := fill(p.hand); err != nil {
+			return nil, err
+		}
+	}
+	if err := fill(deck); err != nil {
+		return nil, err
+	}
+	if err := fill(reinc); err != nil {
+		return nil, err
+	}
+	if !deckGiven {
+		deck, pool = pool, nil
+	}
+	for i := 0; len(pool) > 0; i++ {
+		p := players[i%len(players)]
+		p.discarded, pool = append(p.discarded, pool[0]), pool[1:]
+	}
+
+	g := &Game{
+		Deck:        &Deck{cards: deck, shuffler: RandomShuffler{}},
+		turn:        turn,
+		boyAppeared: boy,
+		out:         ioutil.Discard,
+	}
+	if rng != nil {
+		g.Deck.shuffler = rngShuffler{rng: rng}
+	}
+	if len(reinc) == 1 {
+		g.Deck.reincCard = reinc[0]
+	}
+	for i, pp := range players {
+		name := formatSeat(i)
+		if names != nil {
+			name = names[i]
+		}
+		p := &Player{
+			id:         PlayerID(i + 1),
+			name:       name,
+			hand:       Hand{cards: append([]int{}, pp.hand...)},
+			discarded:  pp.discarded,
+			protected:  pp.protected,
+			calledWise: pp.wise,
+			dropped:    pp.dropped,
+			strategy:   RandomStrategy{rng: rng},
+		}
+		for s, c := range pp.known {
+			p.know(PlayerID(s+1), c)
+		}
+		g.Players = append(g.Players, p)
+	}
+
+	p := g.Players[current]
+	if p.Hand().Count() == 2 {
+		g.beginDiscard(p)
+	} else {
+		g.beginTurn()
+		g.advance()
+	}
+	return g, nil
+}
+
+func parseYesNo(s string) (bool, error) {
+	switch s {
+	case "yes":
+		return true, nil
+	case "no":
+		return false, nil
+	}
+	return false, fmt.Errorf("yes or no is required: %q", s)
+}
+
+// ?は0にする
+func parsePositionCards(s string) ([]int, error) {
+	cards := []int{}
+	for _, f := range strings.Fields(s) {
+		if f == "?" {
+			cards = append(cards, 0)
+			continue
+		}
+		c, err := strconv.Atoi(f)
+		if err != nil || c < 1 || c > 10 {
+			return nil, fmt.Errorf("invalid card: %q", f)
+		}
+		cards = append(cards, c)
+	}
+	return cards, nil
+}
+
+func parsePositionPlayer(s string) (*positionPlayer, error) {
+	p := &positionPlayer{hand: []int{}, known: map[int]int{}}
+	for _, item := range strings.Split(s, ";") {
+		fields := strings.Fields(item)
+		if len(fields) == 0 {
+			continue
+		}
+		var err error
+		switch fields[0] {
+		case "hand":
+			p.hand, err = parsePositionCards(strings.Join(fields[1:], " "))
+		case "discarded":
+			p.discarded, err = parsePositionCards(strings.Join(fields[1:], " "))
+			for _, c := range p.discarded {
+				if c == 0 {
+					err = fmt.Errorf("discarded cards must be known")
+				}
+			}
+		case "protected":
+			p.protected = true
+		case "wise":
+			p.wise = true
+		case "dropped":
+			p.dropped = true
+		case "known":
+			for _, k := range fields[1:] {
+				i := strings.Index(k, "=")
+				if i < 0 {
+					return nil, fmt.Errorf("invalid known: %q", k)
+				}
+				seat, err := parseSeat(k[:i])
+				if err != nil {
+					return nil, err
+				}
+				c, err := strconv.Atoi(k[i+1:])
+				if err != nil || c < 1 || c > 10 {
+					return nil, fmt.Errorf("invalid known: %q", k)
+				}
+				p.known[seat] = c
+			}
+		default:
+			return nil, fmt.Errorf("unknown item: %q", item)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// FormatPosition writes g in the position notation.
+// g must be waiting for a discard or between turns, where the next turn is g.Turn().
+func FormatPosition(g *Game) (string, error) {
+	if t := g.pending.Type; t != DecisionNone && t != DecisionDiscard {
+		return "", fmt.Errorf("position cannot be written while waiting for %v", t)
+	}
+	var b strings.Builder
+	var names []string
+	for _, p := range g.Players {
+		if strings.ContainsAny(p.name, ",#\n") {
+			return "", fmt.Errorf("player name %q cannot be written", p.name)
+		}
+		names = append(names, p.name)
+	}
+	fmt.Fprintf(&b, "players: %s\n", strings.Join(names, ", "))
+	fmt.Fprintf(&b, "turn: %d\n", g.turn)
+	boy := "no"
+	if g.boyAppeared {
+		boy = "yes"
+	}
+	fmt.Fprintf(&b, "boy: %s\n", boy)
+	fmt.Fprintf(&b, "deck: %s\n", joinInts(g.Deck.cards))
+	if g.Deck.reincCard == 0 {
+		fmt.Fprintln(&b, "reincarnation: -")
+	} else {
+		fmt.Fprintf(&b, "reincarnation: %d\n", g.Deck.reincCard)
+	}
+	for i, p := range g.Players {
+		items := []string{"hand " + joinInts(p.hand.cards)}
+		if len(p.discarded) > 0 {
+			items = append(items, "discarded "+joinInts(p.discarded))
+		}
+		if p.protected {
+			items = append(items, "protected")
+		}
+		if p.calledWise {
+			items = append(items, "wise")
+		}
+		if p.dropped {
+			items = append(items, "dropped")
+		}
+		var known []string
+		for _, q := range g.Players {
+			if c, ok := p.known[q.id]; ok {
+				known = append(known, fmt.Sprintf("%s=%d", formatSeat(g.Seat(q)), c))
+			}
+		}
+		sort.Strings(known)
+		if len(known) > 0 {
+			items = append(items, "known "+strings.Join(known, " "))
+		}
+		fmt.Fprintf(&b, "%s: %s\n", formatSeat(i), strings.Join(items, "; "))
+	}
+	return b.String(), nil
+}
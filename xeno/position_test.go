@@ -0,0 +1,115 @@
+package xeno
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestFormatPosition(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	positions := 0
+	for i := 0; i < 30; i++ {
+		g := newSeededGame(2+i%3, rng)
+		g.Start()
+		for !g.Over() {
+			if g.Pending().Type == DecisionDiscard {
+				text, err := FormatPosition(g)
+				if err != nil {
+					t.Fatal(err)
+				}
+				parsed, err := ParsePosition(text, rng)
+				if err != nil {
+					t.Fatalf("%v\n%s", err, text)
+				}
+				if got, _ := FormatPosition(parsed); got != text {
+					t.Fatalf("want:\n%s\ngot:\n%s", text, got)
+				}
+				if parsed.Pending().Seat != g.Pending().Seat {
+					t.Errorf("want: %v, got: %v", g.Pending(), parsed.Pending())
+				}
+				positions++
+			}
+			v := g.View(g.Players[g.Pending().Seat])
+			g.Step(LegalActions(v)[rng.Intn(len(LegalActions(v)))])
+		}
+	}
+	if positions == 0 {
+		t.Error("no position")
+	}
+}
+
+func TestParsePosition(t *testing.T) {
+	text := `
+players: Alice, Bob
+turn: 3  # Bobの手番
+boy: yes
+deck: ? ? ?
+P1: hand 5; discarded 1 2; protected
+P2: hand 6 9; discarded 4; known P1=5
+`
+	g, err := ParsePosition(text, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, bob := g.Players[0], g.Players[1]
+	if d := g.Pending(); d.Type != DecisionDiscard || d.Seat != 1 {
+		t.Errorf("pending: %v", d)
+	}
+	if alice.Name() != "Alice" || !alice.Protected() || alice.Hand().Get() != 5 {
+		t.Errorf("alice: %v %v %v", alice.Name(), alice.Protected(), alice.Hand())
+	}
+	if len(g.Deck.cards) != 3 || g.Deck.reincCard == 0 || !g.boyAppeared {
+		t.Errorf("deck: %v %d", g.Deck.cards, g.Deck.reincCard)
+	}
+
+	// 全部で18枚になる
+	count := map[int]int{}
+	add := func(cards ...int) {
+		for _, c := range cards {
+			count[c]++
+		}
+	}
+	for _, p := range g.Players {
+		add(p.hand.cards...)
+		add(p.discarded...)
+	}
+	add(g.Deck.cards...)
+	add(g.Deck.reincCard)
+	want := map[int]int{}
+	for _, c := range AllCards {
+		want[c]++
+	}
+	for c := 1; c <= 10; c++ {
+		if count[c] != want[c] {
+			t.Errorf("[%d]: want: %d, got: %d", c, want[c], count[c])
+		}
+	}
+	if len(bob.known) != 1 || bob.known[alice.id] != 5 {
+		t.Errorf("known: %v", bob.known)
+	}
+
+	// 手番の人が1枚なら山札を引いて始める
+	g, err = ParsePosition("deck: 3 ?\nP1: hand 8\nP2: hand ?", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := g.Players[0].Hand().cards; len(got) != 2 || got[1] != 3 {
+		t.Errorf("hand: %v", got)
+	}
+
+	for _, s := range []string{
+		"P1: hand 1",
+		"P1: hand 1\nP3: hand 2",
+		"P1: hand 10 10\nP2: hand 1",
+		"P1: hand 1\nP2: hand 2 3",
+		"P1: hand 1\nP2: hand 2; dropped",
+		"P1: hand 1; known P1=2\nP2: hand 2",
+		"P1: hand 1; flying\nP2: hand 2",
+		"players: A\nP1: hand 1\nP2: hand 2",
+		"deck: ? ? ? ? ? ? ? ? ? ? ? ? ? ? ? ?\nP1: hand ?\nP2: hand ?",
+	} {
+		if _, err := ParsePosition(s, nil); err == nil {
+			t.Errorf("%q: want: error", s)
+		}
+	}
+}
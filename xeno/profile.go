@@ -0,0 +1,272 @@
+package xeno
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ProfilesVersion is the format version of Profiles files
+const ProfilesVersion = 1
+
+// Profile は1人のプレイヤーの通算成績。PlayerConfig.Profile などの変わらないキーで区別する
+type Profile struct {
+	Key            string      `json:"key"`
+	Name           string      `json:"name"` // 最後に使った名前
+	Games          int         `json:"games"`
+	Wins           int         `json:"wins"`
+	Losses         int         `json:"losses"`
+	Draws          int         `json:"draws"`        // 勝者がいないゲーム
+	Discards       map[int]int `json:"discards"`     // 自分で捨てたカード→回数
+	Eliminations   map[int]int `json:"eliminations"` // 脱落の原因のカード→回数。0は最後の比較で負けた
+	Investigations int         `json:"investigations"`
+	Hits           int         `json:"hits"` // 当たった捜査
+	LastPlayed     time.Time   `json:"last_played"`
+}
+
+func (p *Profile) WinRate() float64 {
+	if p.Games == 0 {
+		return 0
+	}
+	return float64(p.Wins) / float64(p.Games)
+}
+
+func (p *Profile) InvestigationAccuracy() float64 {
+	if p.Investigations == 0 {
+		return 0
+	}
+	return float64(p.Hits) / float64(p.Investigations)
+}
+
+// FavoriteCards returns the discarded cards, most often first
+func (p *Profile) FavoriteCards() []int {
+	var cards []int
+	for c := range p.Discards {
+		cards = append(cards, c)
+	}
+	sort.Slice(cards, func(i, j int) bool {
+		a, b := p.Discards[cards[i]], p.Discards[cards[j]]
+		return a > b || (a == b && cards[i] < cards[j])
+	})
+	return cards
+}
+
+// Print writes p to w
+func (p *Profile) Print(w io.Writer) {
+	fmt.Fprintf(w, "%s (%s)\n", p.Name, p.Key)
+	fmt.Fprintf(w, "対局 %d  勝ち %d  負け %d  勝者なし %d  勝率 %.1f%%\n", p.Games, p.Wins, p.Losses, p.Draws, 100*p.WinRate())
+	if !p.LastPlayed.IsZero() {
+		fmt.Fprintf(w, "最後の対局 %s\n", p.LastPlayed.Format("2006-01-02 15:04"))
+	}
+	fmt.Fprint(w, "よく捨てるカード:")
+	for i, c := range p.FavoriteCards() {
+		if i == 3 {
+			break
+		}
+		fmt.Fprintf(w, " [%d]%d回", c, p.Discards[c])
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "捜査 %d回  的中 %d回 (%.1f%%)\n", p.Investigations, p.Hits, 100*p.InvestigationAccuracy())
+	fmt.Fprint(w, "脱落の原因:")
+	var causes []int
+	for c := range p.Eliminations {
+		causes = append(causes, c)
+	}
+	sort.Ints(causes)
+	for _, c := range causes {
+		if c == 0 {
+			fmt.Fprintf(w, " 最後の比較%d回", p.Eliminations[c])
+		} else {
+			fmt.Fprintf(w, " [%d]%d回", c, p.Eliminations[c])
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// Profiles はプレイヤーの通算成績の集まり。ファイルに保存して対局のたびに更新する
+type Profiles struct {
+	Version int                 `json:"version"`
+	Players map[string]*Profile `json:"players"`
+}
+
+func NewProfiles() *Profiles {
+	return &Profiles{Version: ProfilesVersion, Players: map[string]*Profile{}}
+}
+
+// LoadProfiles reads profiles written by Profiles.Save
+func LoadProfiles(r io.Reader) (*Profiles, error) {
+	ps := NewProfiles()
+	if err := json.NewDecoder(r).Decode(ps); err != nil {
+		return nil, err
+	}
+	if ps.Version != ProfilesVersion {
+		return nil, fmt.Errorf("unsupported profiles version: %d", ps.Version)
+	}
+	return ps, nil
+}
+
+func (ps *Profiles) Save(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(ps)
+}
+
+// DefaultProfileFile returns the profiles file in the user config directory
+func DefaultProfileFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "xeno", "profiles.json"), nil
+}
+
+// LoadProfileFile reads the profiles file at path. A missing file means no profiles.
+func LoadProfileFile(path string) (*Profiles, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return NewProfiles(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadProfiles(f)
+}
+
+// SaveFile writes ps to path. The file is replaced at once so that it is not broken halfway.
+func (ps *Profiles) SaveFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), ".profiles-*")
+	if err != nil {
+		return err
+	}
+	if err := ps.Save(f); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Profile returns the profile of key, creating it if needed
+func (ps *Profiles) Profile(key string) *Profile {
+	p, ok := ps.Players[key]
+	if !ok {
+		p = &Profile{Key: key, Discards: map[int]int{}, Eliminations: map[int]int{}}
+		ps.Players[key] = p
+	}
+	return p
+}
+
+// Add replays the finished game r and adds it to the profile of keys[seat].
+// An empty key is a player not to be recorded.
+func (ps *Profiles) Add(keys []string, r *Record) error {
+	if len(keys) != len(r.Players) {
+		return fmt.Errorf("%d keys for %d players", len(keys), len(r.Players))
+	}
+	counter := &profileCounter{games: make([]Profile, len(keys))}
+	observed := false
+	g, err := r.Replay(func(g *Game, m Move) {
+		if !observed {
+			// 最初の判断より前は配るだけなので数えない
+			g.AddObserver(counter)
+			observed = true
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if !g.Over() {
+		return fmt.Errorf("game is not over")
+	}
+
+	winners := g.Winners()
+	now := time.Now()
+	for seat, key := range keys {
+		if key == "" {
+			continue
+		}
+		p := ps.Profile(key)
+		if p.Discards == nil {
+			p.Discards = map[int]int{}
+		}
+		if p.Eliminations == nil {
+			p.Eliminations = map[int]int{}
+		}
+		c := counter.games[seat]
+		p.Name = r.Players[seat]
+		p.Games++
+		switch {
+		case len(winners) == 0:
+			p.Draws++
+		case !g.Players[seat].Dropped():
+			p.Wins++
+		default:
+			p.Losses++
+		}
+		for card, n := range c.Discards {
+			p.Discards[card] += n
+		}
+		for card, n := range c.Eliminations {
+			p.Eliminations[card] += n
+		}
+		p.Investigations += c.Investigations
+		p.Hits += c.Hits
+		p.LastPlayed = now
+	}
+	return nil
+}
+
+// 記録したゲームを通算成績のファイルに加える
+func (g *Game) updateProfiles() {
+	if g.profileFile == "" || g.record == nil {
+		return
+	}
+	ps, err := LoadProfileFile(g.profileFile)
+	if err == nil {
+		err = ps.Add(g.profileKeys, g.record)
+	}
+	if err == nil {
+		err = ps.SaveFile(g.profileFile)
+	}
+	if err != nil {
+		g.println("成績を保存できません:", err)
+	}
+}
+
+// 1ゲームの出来事を席ごとに数える
+type profileCounter struct {
+	games []Profile
+}
+
+func (c *profileCounter) OnEvent(g *Game, e Event) {
+	p := &c.games[e.Seat]
+	switch e.Type {
+	case EventDiscard:
+		if p.Discards == nil {
+			p.Discards = map[int]int{}
+		}
+		p.Discards[e.Card]++
+	case EventDropout:
+		if p.Eliminations == nil {
+			p.Eliminations = map[int]int{}
+		}
+		p.Eliminations[e.Card]++
+	case EventInvestigation:
+		p.Investigations++
+		if e.Hit {
+			p.Hits++
+		}
+	}
+}
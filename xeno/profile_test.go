@@ -0,0 +1,125 @@
+package xeno
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProfiles_Add(t *testing.T) {
+	ps := NewProfiles()
+	discards, games := 0, 0
+	Simulate(SimulationConfig{Games: 20, Players: 3, Seed: 5, Strategy: func(seat int, rng *rand.Rand) PlayerStrategy {
+		return RandomStrategy{rng: rng}
+	}}, func(i int, r *Record, g *Game) {
+		if err := ps.Add([]string{"alice", "", "bob"}, r); err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range r.Moves {
+			if m.Seat == 0 && m.Action.Type() == DecisionDiscard {
+				discards++
+			}
+		}
+		games++
+	})
+
+	if len(ps.Players) != 2 {
+		t.Fatalf("profiles: %v", ps.Players)
+	}
+	a := ps.Players["alice"]
+	if a.Games != games || a.Wins+a.Losses+a.Draws != games || a.Name != "プレイヤー1" {
+		t.Errorf("got: %+v", a)
+	}
+	n := 0
+	for _, c := range a.Discards {
+		n += c
+	}
+	if n != discards {
+		t.Errorf("discards: want: %d, got: %d", discards, n)
+	}
+	eliminated := 0
+	for _, c := range a.Eliminations {
+		eliminated += c
+	}
+	if eliminated != a.Losses+a.Draws {
+		t.Errorf("eliminations: %v, losses: %d", a.Eliminations, a.Losses)
+	}
+	if a.Hits > a.Investigations {
+		t.Errorf("hits: %d, investigations: %d", a.Hits, a.Investigations)
+	}
+
+	r := &Record{Players: []string{"a", "b"}, Deck: AllCards}
+	if err := ps.Add([]string{"a"}, r); err == nil {
+		t.Error("want: error for the number of keys")
+	}
+	if err := ps.Add([]string{"a", "b"}, r); err == nil {
+		t.Error("want: error for an unfinished game")
+	}
+}
+
+func TestProfile_FavoriteCards(t *testing.T) {
+	p := &Profile{Discards: map[int]int{1: 3, 4: 5, 2: 3}}
+	if got, want := p.FavoriteCards(), []int{4, 1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("want: %v, got: %v", want, got)
+	}
+}
+
+func TestProfiles_SaveFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xeno")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "sub", "profiles.json")
+
+	ps, err := LoadProfileFile(path)
+	if err != nil || len(ps.Players) != 0 {
+		t.Fatalf("missing file: %v %v", ps, err)
+	}
+	p := ps.Profile("alice")
+	p.Games, p.Discards[3] = 2, 1
+	if err := ps.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadProfileFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, ps) {
+		t.Errorf("want: %+v, got: %+v", ps.Players["alice"], loaded.Players["alice"])
+	}
+}
+
+func TestNewGame_Profiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xeno")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "profiles.json")
+
+	for i := 0; i < 2; i++ {
+		g, err := BuildGame(GameConfig{
+			Players: []PlayerConfig{
+				{Name: "Alice", Strategy: RandomStrategy{}, Profile: "alice"},
+				{Name: "COM", Strategy: RandomStrategy{}},
+			},
+			Profiles: path,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		g.SetOutput(ioutil.Discard)
+		g.Loop()
+	}
+	ps, err := LoadProfileFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ps.Players) != 1 || ps.Players["alice"].Games != 2 || ps.Players["alice"].Name != "Alice" {
+		t.Errorf("got: %+v", ps.Players)
+	}
+}
@@ -0,0 +1,116 @@
+package xeno
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"strings"
+)
+
+// Puzzle は局面の記法で書いた局面で、判断する人の最善手を当てる問題。
+// ファイルには --- だけの行で区切って何問でも書ける。各問の最初の # の行が題になる。
+//
+//	# 対決するか守るか
+//	deck: ? ?
+//	P1: hand 6 4; discarded 2 3
+//	P2: hand ?; discarded 1 5
+//	---
+//	...
+type Puzzle struct {
+	Title    string
+	Position string
+}
+
+// PuzzleResult は答えた手の判定
+type PuzzleResult struct {
+	Correct bool
+	Answer  ActionValue
+	Best    []ActionValue // 最善の勝率の手。複数あればどれも正解
+	Values  []ActionValue // 全ての合法手。良い順
+}
+
+// 勝率が同じとみなす差
+const puzzleEpsilon = 1e-9
+
+// ParsePuzzles parses puzzles separated by "---" lines. Each position is checked by ParsePosition.
+func ParsePuzzles(text string) ([]Puzzle, error) {
+	var puzzles []Puzzle
+	var lines []string
+	flush := func() error {
+		body := strings.Join(lines, "\n")
+		lines = nil
+		if strings.TrimSpace(body) == "" {
+			return nil
+		}
+		p := Puzzle{Position: body}
+		for _, l := range strings.Split(body, "\n") {
+			if l = strings.TrimSpace(l); strings.HasPrefix(l, "#") {
+				p.Title = strings.TrimSpace(strings.TrimLeft(l, "#"))
+				break
+			}
+		}
+		if _, err := ParsePosition(p.Position, nil); err != nil {
+			return fmt.Errorf("puzzle %d: %v", len(puzzles)+1, err)
+		}
+		puzzles = append(puzzles, p)
+		return nil
+	}
+	for _, l := range strings.Split(text, "\n") {
+		if strings.TrimSpace(l) == "---" {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		lines = append(lines, l)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return puzzles, nil
+}
+
+// LoadPuzzles reads puzzles written for ParsePuzzles
+func LoadPuzzles(r io.Reader) ([]Puzzle, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePuzzles(string(b))
+}
+
+// Game builds the position of p. Unknown cards are dealt by rng.
+// The solver's answer doesn't depend on rng since it only uses what the player to decide can see.
+func (p Puzzle) Game(rng *rand.Rand) (*Game, error) {
+	return ParsePosition(p.Position, rng)
+}
+
+// SolvePuzzle returns the win probabilities of the pending decision of g, seen by the player to decide
+func SolvePuzzle(g *Game, conf SolverConfig) ([]ActionValue, error) {
+	d := g.Pending()
+	if d.Type == DecisionNone {
+		return nil, ErrNoDecision
+	}
+	return NewSolver(conf).Solve(g.View(g.Players[d.Seat]))
+}
+
+// CheckAnswer judges a by values returned by SolvePuzzle
+func CheckAnswer(values []ActionValue, a Action) (PuzzleResult, error) {
+	r := PuzzleResult{Values: values}
+	found := false
+	for _, av := range values {
+		if math.Abs(av.Win-values[0].Win) < puzzleEpsilon {
+			r.Best = append(r.Best, av)
+		}
+		if av.Action == a {
+			r.Answer, found = av, true
+		}
+	}
+	if !found {
+		return PuzzleResult{}, fmt.Errorf("illegal action: %v", a)
+	}
+	r.Correct = math.Abs(r.Answer.Win-values[0].Win) < puzzleEpsilon
+	return r, nil
+}
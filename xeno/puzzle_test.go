@@ -0,0 +1,66 @@
+package xeno
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+const testPuzzles = `
+# 知っている手札を捜査する
+deck: ?
+reincarnation: -
+P1: hand 2 10; known P2=8
+P2: hand 8
+---
+# 2問目
+deck: ? ?
+P1: hand 6 4
+P2: hand ?
+`
+
+func TestParsePuzzles(t *testing.T) {
+	puzzles, err := ParsePuzzles(testPuzzles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(puzzles) != 2 || puzzles[0].Title != "知っている手札を捜査する" || puzzles[1].Title != "2問目" {
+		t.Fatalf("got: %+v", puzzles)
+	}
+	if _, err := ParsePuzzles(testPuzzles + "---\nP1: hand 1"); err == nil || !strings.Contains(err.Error(), "puzzle 3") {
+		t.Errorf("want: error of puzzle 3, got: %v", err)
+	}
+}
+
+func TestCheckAnswer(t *testing.T) {
+	puzzles, err := ParsePuzzles(testPuzzles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := puzzles[0].Game(rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := SolvePuzzle(g, SolverConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := CheckAnswer(values, NewDiscardAction(2, 1, 8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Correct || r.Answer.Win != 1 || len(r.Best) != 1 {
+		t.Errorf("got: %+v", r)
+	}
+	r, err = CheckAnswer(values, NewDiscardAction(2, 1, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Correct || r.Best[0].Action != NewDiscardAction(2, 1, 8) {
+		t.Errorf("got: %+v", r)
+	}
+	if _, err := CheckAnswer(values, NewWiseAction(3)); err == nil {
+		t.Error("want: error")
+	}
+}
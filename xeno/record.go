@@ -0,0 +1,176 @@
+package xeno
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+)
+
+// RecordVersion is the format version of Record
+const RecordVersion = 1
+
+// Move は1つの判断
+type Move struct {
+	Seat   int    `json:"seat"`
+	Action Action `json:"action"`
+}
+
+// Record は対局の記録。配る前の山札、全ての判断、賢者の後にシャッフルした山札から対局を再現できる
+type Record struct {
+	Version  int       `json:"version"`
+	Players  []string  `json:"players"`
+	Deck     []int     `json:"deck"` // 最後が転生札
+	Moves    []Move    `json:"moves"`
+	Shuffles [][]int   `json:"shuffles,omitempty"`
+	Timeouts []Timeout `json:"timeouts,omitempty"` // 時間切れで代わりに打った判断。手はMovesにもある
+}
+
+// StartRecording records g from now. Call it before Start or Loop.
+// Clones of g are not recorded.
+func (g *Game) StartRecording() *Record {
+	r := &Record{
+		Version: RecordVersion,
+		Deck:    append(append([]int{}, g.Deck.cards...), g.Deck.reincCard),
+		Moves:   []Move{},
+	}
+	for _, p := range g.Players {
+		r.Players = append(r.Players, p.Name())
+	}
+	g.record = r
+	return r
+}
+
+// Recording returns the record started by StartRecording, or nil if g is not recorded.
+// The record of a loaded game is kept by Save and Load.
+func (g *Game) Recording() *Record {
+	return g.record
+}
+
+func (g *Game) recordMove(seat int, a Action) {
+	if g.record != nil {
+		g.record.Moves = append(g.record.Moves, Move{Seat: seat, Action: a})
+	}
+}
+
+func (g *Game) recordTimeout(t Timeout) {
+	if g.record != nil {
+		g.record.Timeouts = append(g.record.Timeouts, t)
+	}
+}
+
+func (g *Game) recordShuffle() {
+	if g.record != nil {
+		g.record.Shuffles = append(g.record.Shuffles, append([]int{}, g.Deck.cards...))
+	}
+}
+
+// replayShuffler は記録したシャッフルの結果を順に返す
+type replayShuffler struct {
+	shuffles [][]int
+}
+
+func (s *replayShuffler) clone() Shuffler {
+	return &replayShuffler{shuffles: s.shuffles}
+}
+
+func (s *replayShuffler) Shuffle(cards []int) []int {
+	if len(s.shuffles) == 0 {
+		return cards
+	}
+	c := append([]int{}, s.shuffles[0]...)
+	s.shuffles = s.shuffles[1:]
+	return c
+}
+
+// Replay plays r again. fn is called with the game waiting for each move, if not nil.
+// Players of the returned game use RandomStrategy and the output is discarded.
+func (r *Record) Replay(fn func(g *Game, m Move)) (*Game, error) {
+	if len(r.Deck) != len(AllCards) {
+		return nil, fmt.Errorf("invalid deck: %v", r.Deck)
+	}
+	g := &Game{
+		Deck: &Deck{
+			cards:     append([]int{}, r.Deck[:len(r.Deck)-1]...),
+			reincCard: r.Deck[len(r.Deck)-1],
+			shuffler:  &replayShuffler{shuffles: r.Shuffles},
+		},
+		out: ioutil.Discard,
+	}
+	for i, name := range r.Players {
+		g.Players = append(g.Players, &Player{
+			id:       PlayerID(i + 1),
+			name:     name,
+			hand:     Hand{cards: []int{}},
+			strategy: RandomStrategy{},
+		})
+	}
+
+	g.Start()
+	for i, m := range r.Moves {
+		if g.Over() {
+			return g, fmt.Errorf("move %d: game is over", i)
+		}
+		if s := g.Pending().Seat; s != m.Seat {
+			return g, fmt.Errorf("move %d: seat %d is deciding, not %d", i, s, m.Seat)
+		}
+		if fn != nil {
+			fn(g, m)
+		}
+		if err := g.Step(m.Action); err != nil {
+			return g, fmt.Errorf("move %d: %v", i, err)
+		}
+	}
+	return g, nil
+}
+
+// Save writes r as JSON
+func (r *Record) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+// LoadRecord reads a record written by Record.Save
+func LoadRecord(rd io.Reader) (*Record, error) {
+	var r Record
+	if err := json.NewDecoder(rd).Decode(&r); err != nil {
+		return nil, err
+	}
+	if r.Version != RecordVersion {
+		return nil, fmt.Errorf("unsupported record version: %d", r.Version)
+	}
+	return &r, nil
+}
+
+// SimulationConfig は Simulate の設定
+type SimulationConfig struct {
+	Games   int
+	Players int // 0なら2人
+	Seed    int64
+	// Strategy returns the strategy of the player at seat. nil means CommStrategy.
+	Strategy func(seat int, rng *rand.Rand) PlayerStrategy
+	// Audit plays games in the audit mode. Violations are found by Game.AuditErr.
+	Audit bool
+}
+
+// Simulate plays games between strategies and calls fn with the record and the final state of each game
+func Simulate(conf SimulationConfig, fn func(i int, r *Record, g *Game)) {
+	if conf.Players == 0 {
+		conf.Players = 2
+	}
+	rng := rand.New(rand.NewSource(conf.Seed))
+	for i := 0; i < conf.Games; i++ {
+		g := newSeededGame(conf.Players, rng)
+		for seat, p := range g.Players {
+			if conf.Strategy != nil {
+				p.strategy = conf.Strategy(seat, rng)
+			} else {
+				p.strategy = NewCommStrategy(rng)
+			}
+		}
+		g.SetAudit(conf.Audit)
+		r := g.StartRecording()
+		g.Loop()
+		fn(i, r, g)
+	}
+}
@@ -0,0 +1,64 @@
+package xeno
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestRecord_Replay(t *testing.T) {
+	shuffled := 0
+	Simulate(SimulationConfig{
+		Games:   50,
+		Players: 3,
+		Seed:    1,
+		Strategy: func(seat int, rng *rand.Rand) PlayerStrategy {
+			return NewExpertStrategy(ExpertConfig{Seed: rng.Int63()})
+		},
+	}, func(i int, r *Record, g *Game) {
+		var buf bytes.Buffer
+		if err := r.Save(&buf); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := LoadRecord(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(r, loaded) {
+			t.Fatalf("loaded record differs: %v %v", r, loaded)
+		}
+
+		replayed, err := loaded.Replay(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !replayed.Over() {
+			t.Fatalf("replayed game is not over")
+		}
+		for s, p := range g.Players {
+			rp := replayed.Players[s]
+			if !reflect.DeepEqual(p.Discarded(), rp.Discarded()) || p.Dropped() != rp.Dropped() {
+				t.Errorf("game %d seat %d: want: %v, got: %v", i, s, p, rp)
+			}
+		}
+		shuffled += len(r.Shuffles)
+	})
+	if shuffled == 0 {
+		t.Errorf("no game used the sage")
+	}
+}
+
+func TestRecord_Replay_Invalid(t *testing.T) {
+	g := newQuietGame(2)
+	r := g.StartRecording()
+	g.Loop()
+
+	r.Moves[0].Seat = 1 - r.Moves[0].Seat
+	if _, err := r.Replay(nil); err == nil {
+		t.Errorf("want: error")
+	}
+	if _, err := LoadRecord(bytes.NewBufferString(`{"version":0}`)); err == nil {
+		t.Errorf("want: error")
+	}
+}
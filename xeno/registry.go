@@ -0,0 +1,396 @@
+package xeno
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StrategyParams は戦略の指定 "name:preset,key=value,..." の引数。
+// key=valueでない引数はプリセットとしてキー""に入る
+type StrategyParams map[string]string
+
+// Preset returns the argument without a key
+func (p StrategyParams) Preset(def string) string {
+	if v, ok := p[""]; ok {
+		return v
+	}
+	return def
+}
+
+func (p StrategyParams) String(key, def string) string {
+	if v, ok := p[key]; ok {
+		return v
+	}
+	return def
+}
+
+func (p StrategyParams) Int(key string, def int) (int, error) {
+	v, ok := p[key]
+	if !ok {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", key, err)
+	}
+	return n, nil
+}
+
+func (p StrategyParams) Int64(key string, def int64) (int64, error) {
+	v, ok := p[key]
+	if !ok {
+		return def, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", key, err)
+	}
+	return n, nil
+}
+
+func (p StrategyParams) Float(key string, def float64) (float64, error) {
+	v, ok := p[key]
+	if !ok {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", key, err)
+	}
+	return f, nil
+}
+
+func (p StrategyParams) Duration(key string, def time.Duration) (time.Duration, error) {
+	v, ok := p[key]
+	if !ok {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", key, err)
+	}
+	return d, nil
+}
+
+// seedが指定されていればその値。なければrngから、rngもなければ時刻から決める
+func (p StrategyParams) seed(rng *rand.Rand) (int64, error) {
+	if _, ok := p["seed"]; ok {
+		return p.Int64("seed", 0)
+	}
+	if rng != nil {
+		return rng.Int63(), nil
+	}
+	return time.Now().UnixNano(), nil
+}
+
+// seedが指定されていればその乱数。なければrngから作った乱数、rngもなければnil
+func (p StrategyParams) rand(rng *rand.Rand) (*rand.Rand, error) {
+	if _, ok := p["seed"]; !ok && rng == nil {
+		return nil, nil
+	}
+	seed, err := p.seed(rng)
+	if err != nil {
+		return nil, err
+	}
+	return rand.New(rand.NewSource(seed)), nil
+}
+
+// 知らない引数があればエラー
+func (p StrategyParams) check(keys ...string) error {
+	for k := range p {
+		known := false
+		for _, key := range keys {
+			known = known || k == key
+		}
+		if !known {
+			if k == "" {
+				return fmt.Errorf("unknown preset: %s", p[k])
+			}
+			return fmt.Errorf("unknown parameter: %s", k)
+		}
+	}
+	return nil
+}
+
+// StrategyFactory builds a strategy from params.
+// rng is the caller's source of the seed when params has no seed. nil means the current time.
+type StrategyFactory func(params StrategyParams, rng *rand.Rand) (PlayerStrategy, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]StrategyFactory{}
+)
+
+// RegisterStrategy registers f under name. It replaces the strategy already registered under name.
+func RegisterStrategy(name string, f StrategyFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = f
+}
+
+// Strategies returns the registered names
+func Strategies() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	var names []string
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseStrategySpec splits spec "name:arg,arg,..." into the name and params
+func ParseStrategySpec(spec string) (string, StrategyParams, error) {
+	name, args := spec, ""
+	if i := strings.Index(spec, ":"); i >= 0 {
+		name, args = spec[:i], spec[i+1:]
+	}
+	params := StrategyParams{}
+	if args == "" {
+		return name, params, nil
+	}
+	for _, arg := range strings.Split(args, ",") {
+		key, value := "", arg
+		if i := strings.Index(arg, "="); i >= 0 {
+			key, value = arg[:i], arg[i+1:]
+		}
+		if _, ok := params[key]; ok {
+			return "", nil, fmt.Errorf("duplicated parameter in %q", spec)
+		}
+		params[key] = value
+	}
+	return name, params, nil
+}
+
+// NewStrategy builds a strategy from spec such as "com", "expert:aggressive" or "mcts:iterations=5000"
+func NewStrategy(spec string) (PlayerStrategy, error) {
+	return NewSeededStrategy(spec, nil)
+}
+
+// NewSeededStrategy is NewStrategy which draws the seed from rng unless spec has one.
+// The same rng gives the same strategy.
+func NewSeededStrategy(spec string, rng *rand.Rand) (PlayerStrategy, error) {
+	name, params, err := ParseStrategySpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	registryMu.RLock()
+	f, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown strategy: %s", name)
+	}
+	s, err := f(params, rng)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return s, nil
+}
+
+// checkStrategy builds the strategy of spec to see that spec is valid and closes it if it is an io.Closer
+func checkStrategy(spec string) error {
+	s, err := NewStrategy(spec)
+	if err != nil {
+		return err
+	}
+	if c, ok := s.(io.Closer); ok {
+		c.Close()
+	}
+	return nil
+}
+
+// closeStrategies closes the strategies of g which are io.Closer, such as ExternalStrategy
+func closeStrategies(g *Game) {
+	for _, p := range g.Players {
+		if c, ok := p.strategy.(io.Closer); ok {
+			c.Close()
+		}
+	}
+}
+
+func init() {
+	RegisterStrategy("com", func(p StrategyParams, rng *rand.Rand) (PlayerStrategy, error) {
+		r, err := p.rand(rng)
+		if err != nil {
+			return nil, err
+		}
+		return NewCommStrategy(r), p.check("seed")
+	})
+	RegisterStrategy("manual", func(p StrategyParams, rng *rand.Rand) (PlayerStrategy, error) {
+		// manual:advisor で助言を表示する
+		if err := p.check(""); err != nil {
+			return nil, err
+		}
+		switch p.Preset("") {
+		case "":
+			return NewManualStrategy(false), nil
+		case "advisor":
+			return NewManualStrategy(true), nil
+		}
+		return nil, fmt.Errorf("unknown preset: %s", p.Preset(""))
+	})
+	RegisterStrategy("random", func(p StrategyParams, rng *rand.Rand) (PlayerStrategy, error) {
+		r, err := p.rand(rng)
+		if err != nil {
+			return nil, err
+		}
+		return RandomStrategy{rng: r}, p.check("seed")
+	})
+	RegisterStrategy("expert", newExpertFromParams)
+	RegisterStrategy("mcts", newISMCTSFromParams)
+	RegisterStrategy("endgame", newEndgameFromParams)
+	RegisterStrategy("cfr", newCFRFromParams)
+	RegisterStrategy("file", newFromParamFile)
+	RegisterStrategy("external", newExternalFromParams)
+}
+
+// expert:preset,temperature=1,seed=1,... 重みはExpertConfigのフィールド名を小文字にしたもの
+func newExpertFromParams(p StrategyParams, rng *rand.Rand) (PlayerStrategy, error) {
+	preset := p.Preset("normal")
+	conf, ok := ExpertPresets[preset]
+	if !ok {
+		return nil, fmt.Errorf("unknown preset: %s", preset)
+	}
+	weights := conf.weights()
+	weights["temperature"] = &conf.Temperature
+	keys := []string{"", "seed"}
+	for key, w := range weights {
+		keys = append(keys, key)
+		v, err := p.Float(key, *w)
+		if err != nil {
+			return nil, err
+		}
+		*w = v
+	}
+	if err := p.check(keys...); err != nil {
+		return nil, err
+	}
+	seed, err := p.seed(rng)
+	if err != nil {
+		return nil, err
+	}
+	conf.Seed = seed
+	return NewExpertStrategy(conf), nil
+}
+
+// mcts:iterations=1000,time=1s,exploration=0.7,seed=1
+func newISMCTSFromParams(p StrategyParams, rng *rand.Rand) (PlayerStrategy, error) {
+	if err := p.check("iterations", "time", "exploration", "seed"); err != nil {
+		return nil, err
+	}
+	var conf ISMCTSConfig
+	var err error
+	if conf.Iterations, err = p.Int("iterations", 0); err != nil {
+		return nil, err
+	}
+	if conf.TimeLimit, err = p.Duration("time", 0); err != nil {
+		return nil, err
+	}
+	if conf.Exploration, err = p.Float("exploration", 0); err != nil {
+		return nil, err
+	}
+	if conf.Seed, err = p.seed(rng); err != nil {
+		return nil, err
+	}
+	return NewISMCTSStrategy(conf), nil
+}
+
+// endgame:fallback=expert,deck=3,states=5040,nodes=200000 fallbackは引数なしの戦略名
+func newEndgameFromParams(p StrategyParams, rng *rand.Rand) (PlayerStrategy, error) {
+	if err := p.check("fallback", "deck", "states", "nodes"); err != nil {
+		return nil, err
+	}
+	fallback, err := NewSeededStrategy(p.String("fallback", "expert"), rng)
+	if err != nil {
+		return nil, err
+	}
+	deck, err := p.Int("deck", 3)
+	if err != nil {
+		return nil, err
+	}
+	var conf SolverConfig
+	if conf.MaxStates, err = p.Int("states", 0); err != nil {
+		return nil, err
+	}
+	if conf.MaxNodes, err = p.Int("nodes", 0); err != nil {
+		return nil, err
+	}
+	return NewEndgameStrategy(fallback, deck, conf), nil
+}
+
+// cfr:policy=file,seed=1
+func newCFRFromParams(p StrategyParams, rng *rand.Rand) (PlayerStrategy, error) {
+	if err := p.check("policy", "seed"); err != nil {
+		return nil, err
+	}
+	path, ok := p["policy"]
+	if !ok {
+		return nil, fmt.Errorf("policy is required")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	policy, err := LoadCFRPolicy(f)
+	if err != nil {
+		return nil, err
+	}
+	seed, err := p.seed(rng)
+	if err != nil {
+		return nil, err
+	}
+	return NewCFRStrategy(policy, seed), nil
+}
+
+// file:path,seed=1 Tuneが書いた引数のファイル
+func newFromParamFile(p StrategyParams, rng *rand.Rand) (PlayerStrategy, error) {
+	if err := p.check("", "seed"); err != nil {
+		return nil, err
+	}
+	path := p.Preset("")
+	if path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	f, err := loadParamFile(path)
+	if err != nil {
+		return nil, err
+	}
+	spec := f.Spec()
+	if seed, ok := p["seed"]; ok {
+		if strings.Contains(spec, ":") {
+			spec += ",seed=" + seed
+		} else {
+			spec += ":seed=" + seed
+		}
+	}
+	return NewSeededStrategy(spec, rng)
+}
+
+// external:cmd=./bot,args=-depth 3,timeout=5s,fallback=expert
+// argsは空白で区切る。fallbackは時間切れ・異常終了のときの戦略で、引数なしの戦略名
+func newExternalFromParams(p StrategyParams, rng *rand.Rand) (PlayerStrategy, error) {
+	if err := p.check("cmd", "args", "timeout", "fallback"); err != nil {
+		return nil, err
+	}
+	conf := ExternalConfig{Command: p.String("cmd", ""), Args: strings.Fields(p.String("args", ""))}
+	if conf.Command == "" {
+		return nil, fmt.Errorf("cmd is required")
+	}
+	var err error
+	if conf.Timeout, err = p.Duration("timeout", 0); err != nil {
+		return nil, err
+	}
+	if conf.Fallback, err = NewSeededStrategy(p.String("fallback", "random"), rng); err != nil {
+		return nil, err
+	}
+	return NewExternalStrategy(conf)
+}
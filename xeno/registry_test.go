@@ -0,0 +1,107 @@
+package xeno
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestParseStrategySpec(t *testing.T) {
+	tests := []struct {
+		spec   string
+		name   string
+		params StrategyParams
+	}{
+		{"com", "com", StrategyParams{}},
+		{"expert:aggressive", "expert", StrategyParams{"": "aggressive"}},
+		{"mcts:iterations=5000,seed=1", "mcts", StrategyParams{"iterations": "5000", "seed": "1"}},
+	}
+	for _, tt := range tests {
+		name, params, err := ParseStrategySpec(tt.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name != tt.name || !reflect.DeepEqual(params, tt.params) {
+			t.Errorf("%s: want: %s %v, got: %s %v", tt.spec, tt.name, tt.params, name, params)
+		}
+	}
+	if _, _, err := ParseStrategySpec("mcts:seed=1,seed=2"); err == nil {
+		t.Errorf("want: error")
+	}
+}
+
+func TestNewStrategy(t *testing.T) {
+	s, err := NewStrategy("expert:aggressive,temperature=2,seed=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ExpertPresets["aggressive"]
+	want.Temperature = 2
+	want.Seed = 3
+	if got := s.(*ExpertStrategy).conf; got != want {
+		t.Errorf("want: %v, got: %v", want, got)
+	}
+
+	s, err = NewStrategy("mcts:iterations=5000,time=1s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf := s.(*ISMCTSStrategy).conf; conf.Iterations != 5000 || conf.TimeLimit.Seconds() != 1 {
+		t.Errorf("unexpected config: %v", conf)
+	}
+
+	for _, spec := range []string{"unknown", "com:x=1", "expert:unknown", "mcts:iterations=x", "cfr"} {
+		if _, err := NewStrategy(spec); err == nil {
+			t.Errorf("%s: want: error", spec)
+		}
+	}
+}
+
+func TestRegisterStrategy(t *testing.T) {
+	RegisterStrategy("test-random", func(p StrategyParams, rng *rand.Rand) (PlayerStrategy, error) {
+		return RandomStrategy{}, p.check()
+	})
+	p, err := BuildPlayer(PlayerConfig{StrategyName: "test-random"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.strategy.(RandomStrategy); !ok {
+		t.Errorf("want: RandomStrategy, got: %T", p.strategy)
+	}
+
+	s := NewExpertStrategy(ExpertConfig{})
+	p = NewPlayer(PlayerConfig{StrategyName: "test-random", Strategy: s})
+	if p.strategy != PlayerStrategy(s) {
+		t.Errorf("want: the given strategy, got: %T", p.strategy)
+	}
+
+	conf := GameConfig{Players: []PlayerConfig{{}, {StrategyName: "unknown"}}}
+	if _, err := BuildGame(conf); err == nil {
+		t.Error("want: error for unknown strategy")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("NewGame should panic for unknown strategy")
+		}
+	}()
+	NewGame(conf)
+}
+
+func TestNewSeededStrategy(t *testing.T) {
+	seedOf := func(spec string, seed int64) int64 {
+		s, err := NewSeededStrategy(spec, rand.New(rand.NewSource(seed)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s.(*ExpertStrategy).conf.Seed
+	}
+	if a, b := seedOf("expert", 1), seedOf("expert", 1); a != b {
+		t.Errorf("want: the same seed for the same rng, got: %d, %d", a, b)
+	}
+	if a, b := seedOf("expert", 1), seedOf("expert", 2); a == b {
+		t.Errorf("want: different seeds for different rngs, got: %d", a)
+	}
+	if s := seedOf("expert:seed=5", 1); s != 5 {
+		t.Errorf("want: the seed in spec, got: %d", s)
+	}
+}
@@ -0,0 +1,330 @@
+package xeno
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// SaveVersion is the format version of Game.Save
+const SaveVersion = 1
+
+// StrategySaver is implemented by strategies which Game.Save can save.
+// kind is the name given to RegisterStrategyLoader and state is restored by the loader.
+type StrategySaver interface {
+	SaveState() (kind string, state []byte, err error)
+}
+
+// StrategyLoader restores a strategy of the game g being loaded from the state written by StrategySaver
+type StrategyLoader func(g *Game, state []byte) (PlayerStrategy, error)
+
+var (
+	loadersMu sync.RWMutex
+	loaders   = map[string]StrategyLoader{}
+)
+
+// RegisterStrategyLoader registers l for strategies saved as kind
+func RegisterStrategyLoader(kind string, l StrategyLoader) {
+	loadersMu.Lock()
+	defer loadersMu.Unlock()
+	loaders[kind] = l
+}
+
+type savedGame struct {
+	Version     int           `json:"version"`
+	Deck        []int         `json:"deck"`
+	Reinc       int           `json:"reincarnation"`
+	Players     []savedPlayer `json:"players"`
+	BoyAppeared bool          `json:"boy_appeared"`
+	Turn        int           `json:"turn"`
+	Pending     Decision      `json:"pending"`
+	Over        bool          `json:"over"`
+	Settings    savedSettings `json:"settings"`
+	Timeouts    []Timeout     `json:"timeouts,omitempty"`
+	Record      *Record       `json:"record,omitempty"`
+}
+
+// GameConfigで決めた設定。FallbackPolicyは関数なので保存できない
+type savedSettings struct {
+	HotSeat     bool        `json:"hot_seat,omitempty"`
+	NoUndo      bool        `json:"no_undo,omitempty"`
+	Audit       map[int]int `json:"audit,omitempty"` // 監査で数えるカード
+	ProfileFile string      `json:"profile_file,omitempty"`
+	ProfileKeys []string    `json:"profile_keys,omitempty"`
+}
+
+type savedPlayer struct {
+	ID         PlayerID         `json:"id"`
+	Name       string           `json:"name"`
+	Hand       []int            `json:"hand"`
+	Discarded  []int            `json:"discarded"`
+	Protected  bool             `json:"protected"`
+	CalledWise bool             `json:"called_wise"`
+	Dropped    bool             `json:"dropped"`
+	Manual     bool             `json:"manual"`
+	Known      map[PlayerID]int `json:"known,omitempty"`
+	Timeout    time.Duration    `json:"timeout,omitempty"`
+	Strategy   savedStrategy    `json:"strategy"`
+}
+
+type savedStrategy struct {
+	Kind  string          `json:"kind"`
+	State json.RawMessage `json:"state,omitempty"`
+}
+
+func saveStrategy(s PlayerStrategy) (savedStrategy, error) {
+	ss, ok := s.(StrategySaver)
+	if !ok {
+		return savedStrategy{}, fmt.Errorf("strategy %T cannot be saved", s)
+	}
+	kind, state, err := ss.SaveState()
+	if err != nil {
+		return savedStrategy{}, err
+	}
+	return savedStrategy{Kind: kind, State: state}, nil
+}
+
+func loadStrategy(g *Game, s savedStrategy) (PlayerStrategy, error) {
+	loadersMu.RLock()
+	l, ok := loaders[s.Kind]
+	loadersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown strategy kind: %s", s.Kind)
+	}
+	return l(g, s.State)
+}
+
+// Save writes the whole state of g including the memory of strategies, the settings given by
+// GameConfig and the record. The state of random sources, observers, the output, the undo history
+// and the fallback policy are not saved; set the fallback again with SetFallback after Load.
+// Every strategy must implement StrategySaver.
+func (g *Game) Save(w io.Writer) error {
+	s := savedGame{
+		Version:     SaveVersion,
+		Deck:        g.Deck.cards,
+		Reinc:       g.Deck.reincCard,
+		BoyAppeared: g.boyAppeared,
+		Turn:        g.turn,
+		Pending:     g.pending,
+		Over:        g.over,
+		Settings: savedSettings{
+			HotSeat:     g.hotSeat,
+			NoUndo:      g.noUndo,
+			Audit:       g.auditCards,
+			ProfileFile: g.profileFile,
+			ProfileKeys: g.profileKeys,
+		},
+		Timeouts: g.timeouts,
+		Record:   g.record,
+	}
+	for _, p := range g.Players {
+		strategy, err := saveStrategy(p.strategy)
+		if err != nil {
+			return fmt.Errorf("%s: %v", p.Name(), err)
+		}
+		s.Players = append(s.Players, savedPlayer{
+			ID:         p.id,
+			Name:       p.name,
+			Hand:       p.hand.cards,
+			Discarded:  p.discarded,
+			Protected:  p.protected,
+			CalledWise: p.calledWise,
+			Dropped:    p.dropped,
+			Manual:     p.manual,
+			Known:      p.known,
+			Timeout:    p.timeout,
+			Strategy:   strategy,
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// Load reads a game written by Game.Save with its settings and record.
+// Continue it with Resume, or with Step if it has a pending decision.
+func Load(r io.Reader) (*Game, error) {
+	var s savedGame
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	if s.Version != SaveVersion {
+		return nil, fmt.Errorf("unsupported save version: %d", s.Version)
+	}
+	g := &Game{
+		Deck: &Deck{
+			cards:     append([]int{}, s.Deck...),
+			reincCard: s.Reinc,
+			shuffler:  RandomShuffler{},
+		},
+		boyAppeared: s.BoyAppeared,
+		turn:        s.Turn,
+		pending:     s.Pending,
+		over:        s.Over,
+		hotSeat:     s.Settings.HotSeat,
+		noUndo:      s.Settings.NoUndo,
+		auditCards:  s.Settings.Audit,
+		profileFile: s.Settings.ProfileFile,
+		profileKeys: s.Settings.ProfileKeys,
+		timeouts:    s.Timeouts,
+		record:      s.Record,
+	}
+	for _, sp := range s.Players {
+		g.Players = append(g.Players, &Player{
+			id:         sp.ID,
+			name:       sp.Name,
+			hand:       Hand{cards: append([]int{}, sp.Hand...)},
+			discarded:  sp.Discarded,
+			protected:  sp.Protected,
+			calledWise: sp.CalledWise,
+			dropped:    sp.Dropped,
+			manual:     sp.Manual,
+			known:      sp.Known,
+			timeout:    sp.Timeout,
+		})
+	}
+	// 戦略はゲームの参照を持つことがあるので、プレイヤーを揃えてから戻す
+	for i, sp := range s.Players {
+		strategy, err := loadStrategy(g, sp.Strategy)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", sp.Name, err)
+		}
+		g.Players[i].strategy = strategy
+	}
+	return g, nil
+}
+
+// Resume continues a loaded game asking the strategies, like Loop.
+// A pending decision is resolved first, otherwise the turn g.Turn() begins.
+func (g *Game) Resume() {
+	g.ResumeContext(context.Background())
+}
+
+type commState struct {
+	OpponentInfo map[PlayerID]int `json:"opponent_info"`
+}
+
+func (s CommStrategy) SaveState() (string, []byte, error) {
+	b, err := json.Marshal(commState{OpponentInfo: s.opponentInfo})
+	return "com", b, err
+}
+
+type manualState struct {
+	Advisor bool `json:"advisor,omitempty"`
+}
+
+// 助言の推定は保存せず、読んだ後の最初の判断で今の状態から推定し直す
+func (s *ManualStrategy) SaveState() (string, []byte, error) {
+	b, err := json.Marshal(manualState{Advisor: s.advisor != nil})
+	return "manual", b, err
+}
+
+func (s RandomStrategy) SaveState() (string, []byte, error) {
+	return "random", nil, nil
+}
+
+type beliefState struct {
+	Seat  int            `json:"seat"`
+	Hands []Distribution `json:"hands"`
+	Drawn []Distribution `json:"drawn"`
+	View  PlayerView     `json:"view"`
+}
+
+type expertState struct {
+	Config ExpertConfig `json:"config"`
+	Belief *beliefState `json:"belief,omitempty"`
+}
+
+func (s *ExpertStrategy) SaveState() (string, []byte, error) {
+	st := expertState{Config: s.conf}
+	if b := s.belief; b != nil {
+		st.Belief = &beliefState{Seat: b.seat, Hands: b.hands, Drawn: b.drawn, View: b.view}
+	}
+	data, err := json.Marshal(st)
+	return "expert", data, err
+}
+
+func (s *ISMCTSStrategy) SaveState() (string, []byte, error) {
+	data, err := json.Marshal(s.conf)
+	return "mcts", data, err
+}
+
+type endgameState struct {
+	Fallback savedStrategy `json:"fallback"`
+	MaxDeck  int           `json:"max_deck"`
+	Config   SolverConfig  `json:"config"`
+}
+
+func (s *EndgameStrategy) SaveState() (string, []byte, error) {
+	fallback, err := saveStrategy(s.fallback)
+	if err != nil {
+		return "", nil, err
+	}
+	data, err := json.Marshal(endgameState{Fallback: fallback, MaxDeck: s.maxDeck, Config: s.solver.conf})
+	return "endgame", data, err
+}
+
+func init() {
+	RegisterStrategyLoader("com", func(g *Game, state []byte) (PlayerStrategy, error) {
+		var st commState
+		if err := json.Unmarshal(state, &st); err != nil {
+			return nil, err
+		}
+		s := NewCommStrategy(nil)
+		for id, c := range st.OpponentInfo {
+			s.opponentInfo[id] = c
+		}
+		return s, nil
+	})
+	RegisterStrategyLoader("manual", func(g *Game, state []byte) (PlayerStrategy, error) {
+		var st manualState
+		if len(state) > 0 {
+			if err := json.Unmarshal(state, &st); err != nil {
+				return nil, err
+			}
+		}
+		s := NewManualStrategy(st.Advisor)
+		s.game = g
+		return s, nil
+	})
+	RegisterStrategyLoader("random", func(g *Game, state []byte) (PlayerStrategy, error) {
+		return RandomStrategy{}, nil
+	})
+	RegisterStrategyLoader("expert", func(g *Game, state []byte) (PlayerStrategy, error) {
+		var st expertState
+		if err := json.Unmarshal(state, &st); err != nil {
+			return nil, err
+		}
+		s := NewExpertStrategy(st.Config)
+		if b := st.Belief; b != nil {
+			s.belief = &Belief{seat: b.Seat, hands: b.Hands, drawn: b.Drawn, view: b.View}
+		}
+		s.game = g
+		return s, nil
+	})
+	RegisterStrategyLoader("mcts", func(g *Game, state []byte) (PlayerStrategy, error) {
+		var conf ISMCTSConfig
+		if err := json.Unmarshal(state, &conf); err != nil {
+			return nil, err
+		}
+		s := NewISMCTSStrategy(conf)
+		s.game = g
+		return s, nil
+	})
+	RegisterStrategyLoader("endgame", func(g *Game, state []byte) (PlayerStrategy, error) {
+		var st endgameState
+		if err := json.Unmarshal(state, &st); err != nil {
+			return nil, err
+		}
+		fallback, err := loadStrategy(g, st.Fallback)
+		if err != nil {
+			return nil, err
+		}
+		s := NewEndgameStrategy(fallback, st.MaxDeck, st.Config)
+		s.game = g
+		return s, nil
+	})
+}
@@ -0,0 +1,172 @@
+package xeno
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGame_Save(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		g := newSeededGame(3, rng)
+		g.Players[0].strategy = NewExpertStrategy(ExpertConfig{Seed: seed})
+		g.Players[1].strategy = NewEndgameStrategy(NewCommStrategy(nil), 3, SolverConfig{})
+		com := NewCommStrategy(nil)
+		com.opponentInfo[g.Players[0].ID()] = 5
+		g.Players[2].strategy = com
+		g.Start()
+		for i := 0; i < 6 && !g.Over(); i++ {
+			v := g.View(g.Players[g.Pending().Seat])
+			actions := LegalActions(v)
+			if err := g.Step(actions[rng.Intn(len(actions))]); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var buf bytes.Buffer
+		if err := g.Save(&buf); err != nil {
+			t.Fatal(err)
+		}
+		saved := buf.String()
+		loaded, err := Load(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf.Reset()
+		if err := loaded.Save(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != saved {
+			t.Fatalf("seed %d: saved again differently:\n%s\n%s", seed, saved, buf.String())
+		}
+
+		if !reflect.DeepEqual(g.Deck.cards, loaded.Deck.cards) || g.Deck.reincCard != loaded.Deck.reincCard ||
+			g.turn != loaded.turn || g.boyAppeared != loaded.boyAppeared || g.over != loaded.over ||
+			!reflect.DeepEqual(g.pending, loaded.pending) {
+			t.Errorf("seed %d: want: %v, got: %v", seed, g, loaded)
+		}
+		for i, p := range g.Players {
+			lp := loaded.Players[i]
+			if p.String() != lp.String() || p.protected != lp.protected || p.calledWise != lp.calledWise ||
+				!reflect.DeepEqual(p.Known(), lp.Known()) {
+				t.Errorf("seed %d seat %d: want: %v, got: %v", seed, i, p, lp)
+			}
+		}
+		if info := loaded.Players[2].strategy.(CommStrategy).opponentInfo; !reflect.DeepEqual(com.opponentInfo, info) {
+			t.Errorf("opponentInfo is not restored: want: %v, got: %v", com.opponentInfo, info)
+		}
+		e := g.Players[0].strategy.(*ExpertStrategy)
+		le := loaded.Players[0].strategy.(*ExpertStrategy)
+		if e.belief != nil && !reflect.DeepEqual(e.belief.Hand(1), le.belief.Hand(1)) {
+			t.Errorf("belief is not restored: %v %v", e.belief.Hand(1), le.belief.Hand(1))
+		}
+
+		loaded.SetOutput(ioutil.Discard)
+		loaded.Resume()
+		if !loaded.Over() {
+			t.Errorf("seed %d: resumed game is not over", seed)
+		}
+	}
+}
+
+func TestGame_Save_Errors(t *testing.T) {
+	g := newQuietGame(2)
+	g.Players[0].strategy = &CFRStrategy{}
+	var buf bytes.Buffer
+	if err := g.Save(&buf); err == nil {
+		t.Error("want: error for a strategy without SaveState")
+	}
+
+	buf.Reset()
+	buf.WriteString(`{"version": 99}`)
+	if _, err := Load(&buf); err == nil {
+		t.Error("want: version error")
+	}
+	buf.Reset()
+	buf.WriteString(`{"version": 1, "players": [{"strategy": {"kind": "unknown"}}]}`)
+	if _, err := Load(&buf); err == nil {
+		t.Error("want: unknown strategy error")
+	}
+}
+
+func TestGame_Save_Settings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xeno")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "profiles.json")
+
+	g, err := BuildGame(GameConfig{
+		Players: []PlayerConfig{
+			{Name: "A", Manual: true, Profile: "a"},
+			{Name: "B", Manual: true, Timeout: time.Minute},
+			{Name: "C"},
+		},
+		HotSeat:         true,
+		DisableUndo:     true,
+		Audit:           true,
+		DecisionTimeout: time.Hour,
+		Profiles:        path,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range g.Players {
+		p.strategy = RandomStrategy{}
+	}
+	g.SetOutput(ioutil.Discard)
+	g.Start()
+	for i := 0; i < 3 && !g.Over(); i++ {
+		if err := g.Step(LegalActions(g.View(g.Players[g.Pending().Seat]))[0]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := g.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.HotSeat() || !loaded.noUndo || !reflect.DeepEqual(loaded.auditCards, g.auditCards) ||
+		loaded.profileFile != path || !reflect.DeepEqual(loaded.profileKeys, g.profileKeys) ||
+		!reflect.DeepEqual(loaded.record, g.record) {
+		t.Errorf("settings are not restored: %+v", loaded)
+	}
+	for i, p := range g.Players {
+		if loaded.Players[i].timeout != p.timeout {
+			t.Errorf("seat %d: want timeout %v, got: %v", i, p.timeout, loaded.Players[i].timeout)
+		}
+	}
+
+	// 読んだゲームもホットシートで続き、手札も山札も見せない
+	var out bytes.Buffer
+	loaded.SetOutput(&out)
+	loaded.SetInput(strings.NewReader(strings.Repeat("\n", 100)))
+	loaded.Resume()
+	for _, screen := range strings.Split(out.String(), clearScreen) {
+		if strings.Contains(screen, "= 山札:") || (strings.Contains(screen, " の手札: ") && !strings.Contains(screen, "に渡してください")) {
+			t.Fatalf("hidden information is shown:\n%s", screen)
+		}
+	}
+	if err := loaded.AuditErr(); err != nil {
+		t.Error(err)
+	}
+	ps, err := LoadProfileFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := ps.Players["a"]; p == nil || p.Games != 1 {
+		t.Errorf("profile is not updated: %+v", ps.Players)
+	}
+}
@@ -0,0 +1,581 @@
+package xeno
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+)
+
+// SolverConfig は Solver の探索の上限
+type SolverConfig struct {
+	MaxStates int // 列挙する隠れた状態の数の上限。0ならデフォルト
+	MaxNodes  int // 読む局面の数の上限。0ならデフォルト
+}
+
+const (
+	defaultSolverMaxStates = 5040
+	defaultSolverMaxNodes  = 200000
+)
+
+var (
+	ErrTooManyStates = errors.New("too many hidden states to solve")
+	ErrTooManyNodes  = errors.New("too many nodes to solve")
+	ErrNoDecision    = errors.New("no decision to solve")
+)
+
+// ActionValue is the win probability of an action
+type ActionValue struct {
+	Action Action
+	Win    float64
+}
+
+// Solver は山札が少なくなった局面で、公開された情報と矛盾しない見えないカードの配り方を全て列挙し、
+// 情報集合ごとのexpectimaxで各合法手の勝率を求める。
+// 各プレイヤーは自分から見える状態 (PlayerView) が同じ局面では同じ手を選び、
+// その手は見える状態と矛盾しない全ての局面の平均で自分の勝率を最大にするもので、
+// 同じ勝率の手が複数あればどれも同じ確率で選ぶ。
+// 相手の捜査は当たるとは限らず、疫病の見えない選択も区別できない局面の平均になる。
+// 賢者の後のシャッフルは全ての並びの平均を取る。
+//
+// 局面の確からしさは配り方と偶然だけで決め、相手の手から手札を推し量ることはしない。
+// また、PlayerViewにない記憶 (以前に見た相手の手札や、誰が自分の手札を知っているか) は使わない。
+type Solver struct {
+	conf  SolverConfig
+	nodes int
+}
+
+func NewSolver(conf SolverConfig) *Solver {
+	if conf.MaxStates <= 0 {
+		conf.MaxStates = defaultSolverMaxStates
+	}
+	if conf.MaxNodes <= 0 {
+		conf.MaxNodes = defaultSolverMaxNodes
+	}
+	return &Solver{conf: conf}
+}
+
+// solverNode は読む局面。同じ手数の局面をまとめて広げ、情報集合ごとに手を決める
+type solverNode struct {
+	g        *Game   // 広げたら捨てる
+	weight   float64 // 配り方を同じ重みとした偶然の確率
+	key      string  // 判断する人から見える状態
+	seat     int
+	actions  []Action        // 合法手 (昇順)
+	children [][]*solverNode // 手ごとの次の局面。賢者の後はシャッフルの並びごとに分かれる
+	values   []float64       // 各席の勝率
+}
+
+// Solve returns the win probability of each legal action of v, best first.
+// See Solver for what is assumed of the players.
+func (s *Solver) Solve(v PlayerView) ([]ActionValue, error) {
+	actions := LegalActions(v)
+	if len(actions) == 0 {
+		return nil, ErrNoDecision
+	}
+	deal, err := newPublicDeal(v)
+	if err != nil {
+		return nil, err
+	}
+	if n := countPermutations(deal.pool); n > s.conf.MaxStates {
+		return nil, ErrTooManyStates
+	}
+
+	var roots []*solverNode
+	permutations(deal.pool, func(pool []int) bool {
+		roots = append(roots, &solverNode{g: deal.fill(pool), weight: 1})
+		return true
+	})
+	s.nodes = len(roots)
+	if err := s.solve(roots); err != nil {
+		return nil, err
+	}
+
+	// 視点のプレイヤーの情報集合の平均
+	key := viewKey(v)
+	wins := make([]float64, len(actions))
+	total := 0.0
+	for _, n := range roots {
+		if n.key != key {
+			continue
+		}
+		total += n.weight
+		for i, a := range actions {
+			j := sort.Search(len(n.actions), func(j int) bool { return n.actions[j] >= a })
+			if j == len(n.actions) || n.actions[j] != a {
+				return nil, fmt.Errorf("solver: %v is not legal", a)
+			}
+			for _, c := range n.children[j] {
+				wins[i] += c.weight * c.values[v.Seat]
+			}
+		}
+	}
+	if total == 0 {
+		return nil, errors.New("solver: no deal is consistent with the view")
+	}
+
+	values := make([]ActionValue, len(actions))
+	for i, a := range actions {
+		values[i] = ActionValue{Action: a, Win: wins[i] / total}
+	}
+	sort.SliceStable(values, func(i, j int) bool {
+		return values[i].Win > values[j].Win
+	})
+	return values, nil
+}
+
+// 同じ手数の局面を全て広げ、次の手数を読んでから、情報集合ごとに手を決める
+func (s *Solver) solve(level []*solverNode) error {
+	var next []*solverNode
+	for _, n := range level {
+		if n.g.Over() {
+			n.values = make([]float64, len(n.g.Players))
+			for _, w := range n.g.Winners() {
+				n.values[n.g.Seat(w)] = 1
+			}
+			n.g = nil
+			continue
+		}
+		if err := s.expand(n); err != nil {
+			return err
+		}
+		seen := map[*solverNode]bool{}
+		for _, cs := range n.children {
+			if seen[cs[0]] {
+				continue
+			}
+			seen[cs[0]] = true
+			next = append(next, cs...)
+		}
+	}
+	if len(next) > 0 {
+		if err := s.solve(next); err != nil {
+			return err
+		}
+	}
+
+	sets := map[string][]*solverNode{}
+	for _, n := range level {
+		if n.children != nil {
+			sets[n.key] = append(sets[n.key], n)
+		}
+	}
+	for _, set := range sets {
+		if err := decide(set); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 情報集合setの全ての局面で、判断する人の勝率の平均が最大になる同じ手を選ぶ。
+// 最大の手が複数あれば、どれも同じ確率で選ぶ
+func decide(set []*solverNode) error {
+	seat := set[0].seat
+	wins := make([]float64, len(set[0].actions))
+	best := -1.0
+	for i := range set[0].actions {
+		for _, n := range set {
+			if len(n.actions) != len(set[0].actions) {
+				return errors.New("solver: legal actions differ in an information set")
+			}
+			for _, c := range n.children[i] {
+				wins[i] += c.weight * c.values[seat]
+			}
+		}
+		if wins[i] > best {
+			best = wins[i]
+		}
+	}
+	var chosen []int
+	for i, w := range wins {
+		if w >= best-1e-9 {
+			chosen = append(chosen, i)
+		}
+	}
+	for _, n := range set {
+		n.values = make([]float64, len(set[0].children[0][0].values))
+		for _, i := range chosen {
+			for _, c := range n.children[i] {
+				for j, v := range c.values {
+					n.values[j] += c.weight * v / n.weight / float64(len(chosen))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// nの全ての合法手の次の局面を作る
+func (s *Solver) expand(n *solverNode) error {
+	g := n.g
+	d := g.Pending()
+	v := g.View(g.Players[d.Seat])
+	n.seat, n.key = d.Seat, viewKey(v)
+	n.actions = LegalActions(v)
+	sort.Slice(n.actions, func(i, j int) bool { return n.actions[i] < n.actions[j] })
+
+	type investigation struct {
+		target int
+		hit    bool
+	}
+	// 捜査ははずれた予想はどれも同じ局面になる
+	seen := map[investigation]int{}
+	n.children = make([][]*solverNode, len(n.actions))
+	for i, a := range n.actions {
+		if a.Type() == DecisionDiscard && a.Card() == 2 {
+			key := investigation{target: a.Target(), hit: g.Players[a.Target()].Hand().Has(a.Expect())}
+			if j, ok := seen[key]; ok {
+				n.children[i] = n.children[j]
+				continue
+			}
+			seen[key] = i
+		}
+		n.children[i] = s.after(n, a)
+		if s.nodes > s.conf.MaxNodes {
+			return ErrTooManyNodes
+		}
+	}
+	n.g = nil
+	return nil
+}
+
+// aを適用した後の局面
+func (s *Solver) after(n *solverNode, a Action) []*solverNode {
+	g := n.g
+	d := g.Pending()
+	if d.Type != DecisionWise {
+		c := g.Clone()
+		c.Step(a)
+		s.nodes++
+		return []*solverNode{{g: c, weight: n.weight}}
+	}
+
+	// 賢者で戻した2枚を含む山札のシャッフルは全ての並びを同じ確率とする
+	cards := append([]int{}, g.Deck.cards...)
+	found := false
+	for _, c := range d.Candidates {
+		if c == a.Card() && !found {
+			found = true
+			continue
+		}
+		cards = append(cards, c)
+	}
+	weight := n.weight / float64(countPermutations(cards))
+	var children []*solverNode
+	permutations(cards, func(order []int) bool {
+		c := g.Clone()
+		c.Deck.shuffler = fixedShuffler(append([]int{}, order...))
+		c.Step(a)
+		s.nodes++
+		children = append(children, &solverNode{g: c, weight: weight})
+		return s.nodes <= s.conf.MaxNodes
+	})
+	return children
+}
+
+// 情報集合を区別する文字列。手札と候補の並びは区別しない。
+// 読む局面ごとに作るので、fmtを使わずに詰める
+func viewKey(v PlayerView) string {
+	b := make([]byte, 0, 64)
+	ints := func(cards []int, sorted bool) {
+		if sorted {
+			cards = append([]int{}, cards...)
+			sort.Ints(cards)
+		}
+		for _, c := range cards {
+			b = strconv.AppendInt(b, int64(c), 10)
+			b = append(b, ',')
+		}
+		b = append(b, '|')
+	}
+	flag := func(f bool) {
+		if f {
+			b = append(b, 'y')
+		} else {
+			b = append(b, 'n')
+		}
+	}
+	ints([]int{v.Seat, v.Turn, v.DeckCount, int(v.Decision), v.Target, v.Trigger}, false)
+	flag(v.Reincarnation)
+	flag(v.BoyAppeared)
+	ints(v.Hand, true)
+	ints(v.Candidates, true)
+	for i, p := range v.Players {
+		known := -1
+		if c, ok := v.Known[i]; ok {
+			known = c
+		}
+		ints([]int{p.HandCount, known}, false)
+		flag(p.Dropped)
+		flag(p.Protected)
+		flag(p.CalledWise)
+		ints(p.Discarded, false)
+	}
+	return string(b)
+}
+
+// publicDeal は公開された情報だけを決めたゲーム。視点のプレイヤーの手札を含め、見えないカードは全て配り直す
+type publicDeal struct {
+	v     PlayerView
+	base  *Game
+	hands []int // 各席の配る手札の枚数
+	wise  int   // 配る賢者の候補の枚数
+	pool  []int // 配るカード(昇順)。手札、賢者の候補、山札、転生札の順に配る
+}
+
+func newPublicDeal(v PlayerView) (*publicDeal, error) {
+	unseen := make(map[int]int, len(AllCards))
+	for _, c := range AllCards {
+		unseen[c]++
+	}
+	see := func(cards []int) {
+		for _, c := range cards {
+			unseen[c]--
+		}
+	}
+
+	deal := &publicDeal{v: v, hands: make([]int, len(v.Players))}
+	players := make([]*Player, len(v.Players))
+	for i, pp := range v.Players {
+		players[i] = &Player{
+			id:         PlayerID(i + 1),
+			name:       pp.Name,
+			hand:       Hand{cards: []int{}},
+			discarded:  append([]int{}, pp.Discarded...),
+			protected:  pp.Protected,
+			calledWise: pp.CalledWise,
+			dropped:    pp.Dropped,
+			strategy:   RandomStrategy{},
+		}
+		see(pp.Discarded)
+		switch {
+		case i == v.Seat:
+			deal.hands[i] = len(v.Hand)
+		case v.Decision == DecisionPublicExecution && i == v.Target:
+			// 公開処刑の対象の手札は全員に見えている
+			players[i].hand.cards = append([]int{}, v.Candidates...)
+			see(v.Candidates)
+		default:
+			deal.hands[i] = pp.HandCount
+		}
+	}
+	need := v.DeckCount
+	for _, n := range deal.hands {
+		need += n
+	}
+	if v.Decision == DecisionWise {
+		deal.wise = len(v.Candidates)
+		need += deal.wise
+	}
+	if v.Reincarnation {
+		need++
+	}
+
+	for c := 1; c <= 10; c++ {
+		if unseen[c] < 0 {
+			return nil, fmt.Errorf("solver: too many cards of %d", c)
+		}
+		for n := 0; n < unseen[c]; n++ {
+			deal.pool = append(deal.pool, c)
+		}
+	}
+	if len(deal.pool) != need {
+		return nil, fmt.Errorf("solver: %d unseen cards for %d places", len(deal.pool), need)
+	}
+
+	deal.base = &Game{
+		Deck:        &Deck{},
+		Players:     players,
+		boyAppeared: v.BoyAppeared,
+		turn:        v.Turn,
+		out:         ioutil.Discard,
+	}
+	return deal, nil
+}
+
+// poolの順に配る。視点のプレイヤーが知っている手札は、その人が前から持っている1枚目とする
+func (deal *publicDeal) fill(pool []int) *Game {
+	v := deal.v
+	g := deal.base.Clone()
+	for i, n := range deal.hands {
+		g.Players[i].hand.cards = append(g.Players[i].hand.cards, pool[:n]...)
+		pool = pool[n:]
+	}
+	candidates := append([]int{}, pool[:deal.wise]...)
+	pool = pool[deal.wise:]
+
+	g.Deck = &Deck{
+		cards:    append([]int{}, pool[:v.DeckCount]...),
+		shuffler: RandomShuffler{},
+	}
+	if v.Reincarnation {
+		g.Deck.reincCard = pool[v.DeckCount]
+	}
+
+	viewer := g.Players[v.Seat]
+	for i := range v.Known {
+		if p := g.Players[i]; p.hand.Count() > 0 {
+			viewer.know(p.ID(), p.hand.cards[0])
+		}
+	}
+
+	if v.Decision != DecisionNone {
+		g.pending = Decision{Type: v.Decision, Seat: v.Seat, Target: v.Target, Trigger: v.Trigger}
+		switch v.Decision {
+		case DecisionWise:
+			g.pending.Candidates = candidates
+		case DecisionPublicExecution:
+			g.pending.Candidates = append([]int{}, v.Candidates...)
+		case DecisionPlague:
+			g.pending.Candidates = append([]int{}, g.Players[v.Target].hand.cards...)
+		}
+	}
+	return g
+}
+
+// fixedShuffler はシャッフルの結果を決めておく
+type fixedShuffler []int
+
+func (s fixedShuffler) Shuffle(cards []int) []int {
+	return append([]int{}, s...)
+}
+
+// permutations calls fn with each distinct order of cards until fn returns false
+func permutations(cards []int, fn func([]int) bool) {
+	p := append([]int{}, cards...)
+	sort.Ints(p)
+	for {
+		if !fn(p) {
+			return
+		}
+		// next permutation
+		i := len(p) - 2
+		for i >= 0 && p[i] >= p[i+1] {
+			i--
+		}
+		if i < 0 {
+			return
+		}
+		j := len(p) - 1
+		for p[j] <= p[i] {
+			j--
+		}
+		p[i], p[j] = p[j], p[i]
+		for l, r := i+1, len(p)-1; l < r; l, r = l+1, r-1 {
+			p[l], p[r] = p[r], p[l]
+		}
+	}
+}
+
+// 異なる並びの数
+func countPermutations(cards []int) int {
+	counts := map[int]int{}
+	n := 1
+	for i, c := range cards {
+		counts[c]++
+		// n = n * (i+1) / counts[c] は常に割り切れる
+		n = n * (i + 1) / counts[c]
+	}
+	return n
+}
+
+// EndgameStrategy は山札が少なくなったらSolverで判断し、それまではfallbackに任せる
+type EndgameStrategy struct {
+	solver   *Solver
+	fallback PlayerStrategy
+	maxDeck  int
+	game     *Game // 直近に通知されたゲーム。公開処刑・疫病の判断で使う
+}
+
+func NewEndgameStrategy(fallback PlayerStrategy, maxDeck int, conf SolverConfig) *EndgameStrategy {
+	return &EndgameStrategy{
+		solver:   NewSolver(conf),
+		fallback: fallback,
+		maxDeck:  maxDeck,
+	}
+}
+
+func (s *EndgameStrategy) inner() PlayerStrategy {
+	return s.fallback
+}
+
+func (s *EndgameStrategy) Clone() PlayerStrategy {
+	c := *s
+	if sc, ok := s.fallback.(StrategyCloner); ok {
+		c.fallback = sc.Clone()
+	}
+	return &c
+}
+
+// 読み切れれば最善手を返す
+func (s *EndgameStrategy) solve(g *Game, p *Player, d Decision) (Action, bool) {
+	if g.Deck.count() > s.maxDeck {
+		return 0, false
+	}
+	values, err := s.solver.Solve(g.view(p, d))
+	if err != nil {
+		return 0, false
+	}
+	return values[0].Action, true
+}
+
+// Decide passes ctx to the fallback
+func (s *EndgameStrategy) Decide(ctx context.Context, g *Game, d Decision) Action {
+	s.game = g
+	if d.Type != DecisionPlague {
+		if a, ok := s.solve(g, g.Players[d.Seat], d); ok {
+			return a
+		}
+	}
+	return askStrategy(ctx, g, s.fallback, d)
+}
+
+func (s *EndgameStrategy) SelectDiscard(g *Game, p *Player) CardEvent {
+	s.game = g
+	if a, ok := s.solve(g, p, Decision{Type: DecisionDiscard, Seat: g.Seat(p)}); ok {
+		return g.actionEvent(a)
+	}
+	return s.fallback.SelectDiscard(g, p)
+}
+
+func (s *EndgameStrategy) SelectFromWise(g *Game, candidates []int) int {
+	s.game = g
+	if a, ok := s.solve(g, g.Players[g.pending.Seat], g.pending); ok {
+		return a.Card()
+	}
+	return s.fallback.SelectFromWise(g, candidates)
+}
+
+func (s *EndgameStrategy) SelectOnPublicExecution(player, target *Player, hand Hand) int {
+	if s.game != nil {
+		if a, ok := s.solve(s.game, player, s.game.pending); ok {
+			return a.Card()
+		}
+	}
+	return s.fallback.SelectOnPublicExecution(player, target, hand)
+}
+
+func (s *EndgameStrategy) SelectOnPlague(player, target *Player, hand Hand) int {
+	// 見えないので読み切っても左右の差はない
+	return s.fallback.SelectOnPlague(player, target, hand)
+}
+
+func (s *EndgameStrategy) KnowByClairvoyance(g *Game, player, target *Player, c int) {
+	s.game = g
+	s.fallback.KnowByClairvoyance(g, player, target, c)
+}
+
+func (s *EndgameStrategy) OnOpponentEvent(g *Game, player, opponent *Player, e CardEvent) {
+	s.game = g
+	s.fallback.OnOpponentEvent(g, player, opponent, e)
+}
+
+func (s *EndgameStrategy) OnEvent(g *Game, e Event) {
+	s.game = g
+	if o, ok := s.fallback.(EventObserver); ok {
+		o.OnEvent(g, e)
+	}
+}
@@ -0,0 +1,116 @@
+package xeno
+
+import (
+	"io/ioutil"
+	"math"
+	"testing"
+)
+
+func TestSolver_Solve(t *testing.T) {
+	v := PlayerView{
+		Seat:  0,
+		Hand:  []int{2, 3},
+		Known: map[int]int{1: 7},
+		Players: []PublicPlayer{
+			{HandCount: 2, Discarded: []int{1, 3, 4, 5, 6, 7}},
+			{HandCount: 1, Discarded: []int{1, 2, 4, 5, 6, 8, 8}},
+		},
+		DeckCount:   2,
+		BoyAppeared: true,
+		Decision:    DecisionDiscard,
+		Target:      -1,
+	}
+	values, err := NewSolver(SolverConfig{}).Solve(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != len(LegalActions(v)) {
+		t.Errorf("want: %d values, got: %v", len(LegalActions(v)), values)
+	}
+	// 知っている7を捜査で当てれば勝つ
+	want := NewDiscardAction(2, 1, 7)
+	if values[0].Action != want || values[0].Win != 1 {
+		t.Errorf("want: %v to win, got: %v", want, values)
+	}
+
+	if _, err := NewSolver(SolverConfig{MaxStates: 1}).Solve(v); err != ErrTooManyStates {
+		t.Errorf("want: %v, got: %v", ErrTooManyStates, err)
+	}
+}
+
+func TestSolver_Solve_HiddenFromOpponent(t *testing.T) {
+	// 7を捨てて6を残すと、相手は2を持って捜査してくる。
+	// 相手から見て自分の手札は6か山札の残りのどちらかなので、当たるのは半分
+	v := PlayerView{
+		Seat: 0,
+		Turn: 12,
+		Hand: []int{7, 6},
+		Players: []PublicPlayer{
+			{HandCount: 2, Discarded: []int{1, 3, 4, 5, 8, 9}},
+			{HandCount: 1, Discarded: []int{1, 4, 5, 6, 7, 8, 10}},
+		},
+		DeckCount:   2,
+		BoyAppeared: true,
+		Decision:    DecisionDiscard,
+		Target:      -1,
+	}
+	values, err := NewSolver(SolverConfig{}).Solve(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, av := range values {
+		// 相手が捜査で当てれば負け、はずせば6で勝つ
+		if av.Action == NewDiscardAction(7, -1, 0) && math.Abs(av.Win-0.5) > 1e-9 {
+			t.Errorf("want: 0.5, got: %v", values)
+		}
+	}
+}
+
+func TestSolver_Random(t *testing.T) {
+	s := NewSolver(SolverConfig{MaxNodes: 20000})
+	for i := 0; i < 15; i++ {
+		g := newQuietGame(2 + i%3)
+		g.Start()
+		for !g.Over() {
+			d := g.Pending()
+			v := g.View(g.Players[d.Seat])
+			if v.DeckCount <= 2 {
+				values, err := s.Solve(v)
+				if err == ErrTooManyStates || err == ErrTooManyNodes {
+					randomStep(t, g)
+					continue
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(values) != len(LegalActions(v)) {
+					t.Fatalf("want: %d values, got: %v", len(LegalActions(v)), values)
+				}
+				for _, av := range values {
+					if av.Win < 0 || av.Win > 1+1e-9 {
+						t.Fatalf("win probability out of range: %v", values)
+					}
+				}
+			}
+			randomStep(t, g)
+		}
+	}
+}
+
+func TestEndgameStrategy(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		g := newQuietGame(2 + i%3)
+		g.Players[0].strategy = NewEndgameStrategy(NewExpertStrategy(ExpertPresets["normal"]), 3, SolverConfig{})
+		g.Loop()
+		if !g.Over() {
+			t.Errorf("game is not over")
+		}
+	}
+	g := newQuietGame(2)
+	g.SetOutput(ioutil.Discard)
+	s := NewEndgameStrategy(NewExpertStrategy(ExpertPresets["normal"]), 3, SolverConfig{})
+	g.Players[1].strategy = s
+	if seat := strategySeat(g, s.fallback); seat != 1 {
+		t.Errorf("want: 1, got: %d", seat)
+	}
+}
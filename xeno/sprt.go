@@ -0,0 +1,209 @@
+package xeno
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"sync"
+)
+
+// SPRTConfig は RunSPRT の設定
+type SPRTConfig struct {
+	Candidate string  // 調べる戦略の指定
+	Baseline  string  // 比べる戦略の指定
+	Elo0      float64 // 帰無仮説 H0: 候補のElo差はElo0
+	Elo1      float64 // 対立仮説 H1: 候補のElo差はElo1。0なら10
+	Alpha     float64 // H0が正しいのにH1を採る確率。0なら0.05
+	Beta      float64 // H1が正しいのにH0を採る確率。0なら0.05
+	MaxGames  int     // これだけ対戦しても決まらなければ打ち切る。0なら無制限
+	Parallel  int     // 並行して対戦するゲームの数。0ならCPUの数
+	Seed      int64
+}
+
+// SPRTResult は検定の結果
+type SPRTResult int
+
+const (
+	SPRTContinue     SPRTResult = iota // まだ決まらない
+	SPRTAccepted                       // H1を採る。候補の方が強い
+	SPRTRejected                       // H0を採る。強いとは言えない
+	SPRTInconclusive                   // MaxGamesで打ち切った
+)
+
+func (r SPRTResult) String() string {
+	switch r {
+	case SPRTAccepted:
+		return "H1 accepted"
+	case SPRTRejected:
+		return "H0 accepted"
+	case SPRTInconclusive:
+		return "inconclusive"
+	}
+	return "running"
+}
+
+// SPRTStatus は途中経過。勝ち負けは候補から見たもの
+type SPRTStatus struct {
+	Games  int
+	Wins   int
+	Losses int
+	Draws  int
+	LLR    float64 // 対数尤度比
+	Lower  float64 // これを下回ればH0を採る
+	Upper  float64 // これを上回ればH1を採る
+	Result SPRTResult
+}
+
+func (s SPRTStatus) String() string {
+	return fmt.Sprintf("games:%d W:%d L:%d D:%d LLR:%.3f [%.3f, %.3f] %v",
+		s.Games, s.Wins, s.Losses, s.Draws, s.LLR, s.Lower, s.Upper, s.Result)
+}
+
+func eloScore(elo float64) float64 {
+	return 1 / (1 + math.Pow(10, -elo/400))
+}
+
+// 勝ち・引き分け・負けの3項分布を正規分布で近似した対数尤度比。
+// 全勝や全敗でも分散が0にならないように、勝ち・引き分け・負けに0.5局ずつ足して見積もる
+func sprtLLR(wins, draws, losses int, elo0, elo1 float64) float64 {
+	games := wins + draws + losses
+	if games == 0 {
+		return 0
+	}
+	n := float64(games) + 1.5
+	w, d, l := (float64(wins)+0.5)/n, (float64(draws)+0.5)/n, (float64(losses)+0.5)/n
+	s := w + d/2
+	variance := w*(1-s)*(1-s) + d*(0.5-s)*(0.5-s) + l*s*s
+	s0, s1 := eloScore(elo0), eloScore(elo1)
+	return float64(games) * (s1 - s0) * (2*s - s0 - s1) / (2 * variance)
+}
+
+func (c *SPRTConfig) defaults() {
+	if c.Elo1 == 0 {
+		c.Elo1 = 10
+	}
+	if c.Alpha <= 0 {
+		c.Alpha = 0.05
+	}
+	if c.Beta <= 0 {
+		c.Beta = 0.05
+	}
+	if c.Parallel <= 0 {
+		c.Parallel = runtime.NumCPU()
+	}
+}
+
+// RunSPRT plays Candidate against Baseline in two-player games, alternating seats,
+// until the sequential probability ratio test decides. progress is called after each game, if not nil.
+func RunSPRT(conf SPRTConfig, progress func(SPRTStatus)) (SPRTStatus, error) {
+	conf.defaults()
+	for _, spec := range []string{conf.Candidate, conf.Baseline} {
+		if err := checkStrategy(spec); err != nil {
+			return SPRTStatus{}, err
+		}
+	}
+	status := SPRTStatus{
+		Lower: math.Log(conf.Beta / (1 - conf.Alpha)),
+		Upper: math.Log((1 - conf.Beta) / conf.Alpha),
+	}
+
+	// 決まったら、まだ対戦中のゲームも止める
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	type result struct {
+		r   float64
+		err error
+	}
+	games := make(chan int)
+	results := make(chan result)
+	var wg sync.WaitGroup
+	for w := 0; w < conf.Parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range games {
+				r, err := sprtGame(ctx, conf, i)
+				if ctx.Err() != nil {
+					return
+				}
+				select {
+				case results <- result{r, err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		defer close(games)
+		for i := 0; conf.MaxGames <= 0 || i < conf.MaxGames; i++ {
+			select {
+			case games <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var err error
+	for res := range results {
+		if res.err != nil {
+			err = res.err
+			break
+		}
+		status.Games++
+		switch res.r {
+		case 1:
+			status.Wins++
+		case -1:
+			status.Losses++
+		default:
+			status.Draws++
+		}
+		status.LLR = sprtLLR(status.Wins, status.Draws, status.Losses, conf.Elo0, conf.Elo1)
+		switch {
+		case status.LLR >= status.Upper:
+			status.Result = SPRTAccepted
+		case status.LLR <= status.Lower:
+			status.Result = SPRTRejected
+		case conf.MaxGames > 0 && status.Games >= conf.MaxGames:
+			status.Result = SPRTInconclusive
+		}
+		if progress != nil {
+			progress(status)
+		}
+		if status.Result != SPRTContinue {
+			break
+		}
+	}
+	// 止めたゲームの後始末を待つ
+	cancel()
+	for range results {
+	}
+	return status, err
+}
+
+// i番目のゲーム。候補から見た結果を返す
+func sprtGame(ctx context.Context, conf SPRTConfig, i int) (float64, error) {
+	rng := rand.New(rand.NewSource(conf.Seed + int64(i)))
+	g := newSeededGame(2, rng)
+	defer closeStrategies(g)
+	seat := i % 2
+	var err error
+	if g.Players[seat].strategy, err = NewSeededStrategy(conf.Candidate, rng); err != nil {
+		return 0, err
+	}
+	if g.Players[1-seat].strategy, err = NewSeededStrategy(conf.Baseline, rng); err != nil {
+		return 0, err
+	}
+	if err := g.LoopContext(ctx); err != nil {
+		return 0, err
+	}
+	return cfrUtility(g, seat), nil
+}
@@ -0,0 +1,73 @@
+package xeno
+
+import (
+	"errors"
+	"math/rand"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSPRTLLR(t *testing.T) {
+	if llr := sprtLLR(60, 0, 40, 0, 10); llr <= 0 {
+		t.Errorf("want: positive, got: %v", llr)
+	}
+	if llr := sprtLLR(40, 0, 60, 0, 10); llr >= 0 {
+		t.Errorf("want: negative, got: %v", llr)
+	}
+	// 全勝でも0にならない
+	if llr := sprtLLR(5, 0, 0, 0, 10); llr <= 0 {
+		t.Errorf("want: positive, got: %v", llr)
+	}
+	if llr := sprtLLR(0, 0, 5, 0, 10); llr >= 0 {
+		t.Errorf("want: negative, got: %v", llr)
+	}
+	if llr := sprtLLR(0, 0, 0, 0, 10); llr != 0 {
+		t.Errorf("want: 0, got: %v", llr)
+	}
+}
+
+func TestRunSPRT(t *testing.T) {
+	tests := []struct {
+		candidate, baseline string
+		want                SPRTResult
+	}{
+		{"expert", "random", SPRTAccepted},
+		{"random", "expert", SPRTRejected},
+	}
+	for _, tt := range tests {
+		games := 0
+		s, err := RunSPRT(SPRTConfig{Candidate: tt.candidate, Baseline: tt.baseline, Elo1: 50, MaxGames: 2000, Parallel: 4}, func(s SPRTStatus) {
+			games = s.Games
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Result != tt.want || games != s.Games {
+			t.Errorf("%s vs %s: want: %v, got: %v", tt.candidate, tt.baseline, tt.want, s)
+		}
+	}
+
+	s, err := RunSPRT(SPRTConfig{Candidate: "random", Baseline: "random", MaxGames: 10}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Result != SPRTInconclusive || s.Games != 10 {
+		t.Errorf("want: inconclusive after 10 games, got: %v", s)
+	}
+
+	if _, err := RunSPRT(SPRTConfig{Candidate: "unknown", Baseline: "com"}, nil); err == nil {
+		t.Errorf("want: error")
+	}
+
+	// 確認のときだけ作れる戦略のエラーも返す
+	var built int32
+	RegisterStrategy("test-once", func(p StrategyParams, rng *rand.Rand) (PlayerStrategy, error) {
+		if atomic.AddInt32(&built, 1) > 1 {
+			return nil, errors.New("test-once: built twice")
+		}
+		return RandomStrategy{rng}, p.check()
+	})
+	if _, err := RunSPRT(SPRTConfig{Candidate: "test-once", Baseline: "random", MaxGames: 10, Parallel: 2}, nil); err == nil {
+		t.Errorf("want: error from the second build")
+	}
+}
@@ -1,73 +1,92 @@
 package xeno
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"math/rand"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 )
 
 type CommStrategy struct {
 	opponentInfo map[PlayerID]int
+	rng          *rand.Rand // nilならmath/randの共有の乱数
 }
 
-func (s CommStrategy) SelectDiscard(g *Game, p *Player) CardEvent {
-	var discard int
-	if p.hand.Has(10) {
-		// 10は選べない
-		discard = p.hand.Another(10)
-	} else {
-		discard = p.hand.Random()
+// NewCommStrategy returns CommStrategy using rng. nil means the shared source of math/rand.
+func NewCommStrategy(rng *rand.Rand) CommStrategy {
+	return CommStrategy{opponentInfo: map[PlayerID]int{}, rng: rng}
+}
+
+func intn(rng *rand.Rand, n int) int {
+	if rng == nil {
+		return rand.Intn(n)
 	}
+	return rng.Intn(n)
+}
+
+func (s CommStrategy) SelectDiscard(g *Game, p *Player) CardEvent {
+	actions := LegalActions(g.discardView(p))
+	cards := discardCards(actions)
+	discard := cards[intn(s.rng, len(cards))]
 
 	event := CardEvent{Card: discard}
+	targets := discardTargets(actions, discard)
 	switch event.Card {
 	case 2:
-		event.Target, event.Expect = s.estimateOpponentHand(g, p)
+		event.Target, event.Expect = s.estimateOpponentHand(g, p, targets)
 	case 1, 3, 5, 9:
-		event.Target = s.randomSelectTarget(g, p)
+		if len(targets) > 0 {
+			event.Target = s.randomSelectTarget(g, targets)
+		}
 	case 6:
 		// TODO: 相手の持っているカードを考慮
-		event.Target = s.randomSelectTarget(g, p)
+		event.Target = s.randomSelectTarget(g, targets)
 	case 8:
-		event.Target = s.randomSelectTarget(g, p)
+		event.Target = s.randomSelectTarget(g, targets)
 		s.opponentInfo[event.Target.ID()] = p.hand.Another(discard)
 	case 4, 7, 10:
 	}
 	return event
 }
 
-func (s CommStrategy) randomSelectTarget(g *Game, p *Player) (target *Player) {
-	others := g.OtherPlayers(p)
-	for {
-		target = others[rand.Intn(len(others))]
-		if !target.Dropped() {
-			break
-		}
+func (s CommStrategy) Clone() PlayerStrategy {
+	info := make(map[PlayerID]int, len(s.opponentInfo))
+	for id, c := range s.opponentInfo {
+		info[id] = c
+	}
+	c := CommStrategy{opponentInfo: info}
+	if s.rng != nil {
+		c.rng = rand.New(rand.NewSource(s.rng.Int63()))
 	}
-	if target == nil {
+	return c
+}
+
+func (s CommStrategy) randomSelectTarget(g *Game, targets []int) (target *Player) {
+	if len(targets) == 0 {
 		log.Fatal("target not found")
 	}
-	return target
+	return g.Players[targets[intn(s.rng, len(targets))]]
 }
 
-func (s CommStrategy) estimateOpponentHand(g *Game, p *Player) (target *Player, card int) {
+func (s CommStrategy) estimateOpponentHand(g *Game, p *Player, targets []int) (target *Player, card int) {
 	// Decide from opponent info
-	if len(s.opponentInfo) > 0 {
-		targetIdx := rand.Intn(len(s.opponentInfo))
-		idx := 0
-		for id, c := range s.opponentInfo {
-			if idx == targetIdx {
-				target = g.Player(id)
-				if target.Dropped() {
-					continue
-				}
-				card = c
-				return
-			}
+	var known []int
+	for _, t := range targets {
+		if _, ok := s.opponentInfo[g.Players[t].ID()]; ok {
+			known = append(known, t)
 		}
 	}
+	if len(known) > 0 {
+		target = g.Players[known[intn(s.rng, len(known))]]
+		card = s.opponentInfo[target.ID()]
+		return
+	}
 
 	// Then, estimate
 	appeared := append([]int{}, p.Hand().Slice()...)
@@ -97,7 +116,7 @@ func (s CommStrategy) estimateOpponentHand(g *Game, p *Player) (target *Player,
 		}
 	}
 
-	debugPrintf("estimateOpponentHand - hidden cards: %v\n", hiddens)
+	g.debugf("estimateOpponentHand - hidden cards: %v\n", hiddens)
 
 	// select candidates from cards which remains largest count
 	candidates := []int{}
@@ -106,16 +125,17 @@ func (s CommStrategy) estimateOpponentHand(g *Game, p *Player) (target *Player,
 			candidates = append(candidates, c)
 		}
 	}
+	sort.Ints(candidates)
 
 	// finally select randomly
-	card = candidates[rand.Intn(len(candidates))]
-	target = s.randomSelectTarget(g, p)
+	card = candidates[intn(s.rng, len(candidates))]
+	target = s.randomSelectTarget(g, targets)
 	return
 }
 
 func (s CommStrategy) SelectFromWise(g *Game, candidates []int) int {
 	// TODO: select logic
-	return candidates[rand.Intn(len(candidates))]
+	return candidates[intn(s.rng, len(candidates))]
 }
 
 func (s CommStrategy) SelectOnPublicExecution(player, target *Player, hand Hand) int {
@@ -123,7 +143,7 @@ func (s CommStrategy) SelectOnPublicExecution(player, target *Player, hand Hand)
 }
 
 func (s CommStrategy) SelectOnPlague(player, target *Player, hand Hand) int {
-	return hand.Random()
+	return hand.At(intn(s.rng, hand.Count()))
 }
 
 func (s CommStrategy) KnowByClairvoyance(g *Game, player, target *Player, c int) {
@@ -138,21 +158,68 @@ func (s CommStrategy) OnOpponentEvent(g *Game, player, opponent *Player, e CardE
 	}
 }
 
-func userInput(candidates []int) (num int) {
+// RandomStrategy は合法手から一様にランダムに選ぶ
+type RandomStrategy struct {
+	rng *rand.Rand // nilならmath/randの共有の乱数
+}
+
+func (s RandomStrategy) SelectDiscard(g *Game, p *Player) CardEvent {
+	actions := LegalActions(g.discardView(p))
+	return g.actionEvent(actions[intn(s.rng, len(actions))])
+}
+
+func (s RandomStrategy) SelectFromWise(g *Game, candidates []int) int {
+	return candidates[intn(s.rng, len(candidates))]
+}
+
+func (s RandomStrategy) SelectOnPublicExecution(player, target *Player, hand Hand) int {
+	return hand.At(intn(s.rng, hand.Count()))
+}
+
+func (s RandomStrategy) SelectOnPlague(player, target *Player, hand Hand) int {
+	return hand.At(intn(s.rng, hand.Count()))
+}
+
+func (s RandomStrategy) KnowByClairvoyance(g *Game, player, target *Player, c int) {}
+
+func (s RandomStrategy) OnOpponentEvent(g *Game, player, opponent *Player, e CardEvent) {}
+
+// errUndo は人間が待ったしたときに入力から返す
+var errUndo = errors.New("undo")
+
+// readで候補を1つ読む。undoができるときだけ待ったを受け付け、待ったならerrUndoを返す。
+// 入力が終わったら最初の候補を選ぶ。ctxが終わったらそのエラーを返す
+func userInput(ctx context.Context, read func(context.Context) (string, error), w io.Writer, candidates []int, undo bool) (num int, err error) {
 	for {
-		fmt.Printf("Select %v to discard\n", candidates)
-		var input string
-		fmt.Scan(&input)
-		//fmt.Printf("%s\n", input)
+		if undo {
+			fmt.Fprintf(w, "Select %v to discard (u: undo)\n", candidates)
+		} else {
+			fmt.Fprintf(w, "Select %v to discard\n", candidates)
+		}
+		input, err := read(ctx)
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		input = strings.TrimSpace(input)
+		if err != nil && input == "" {
+			fmt.Fprintln(w, "no input")
+			if len(candidates) == 0 {
+				return 0, nil
+			}
+			return candidates[0], nil
+		}
+		if undo && (input == "u" || input == "undo") {
+			return 0, errUndo
+		}
 		i, err := strconv.Atoi(input)
 		if err != nil {
-			fmt.Printf("invalid input: %s\n", input)
+			fmt.Fprintf(w, "invalid input: %s\n", input)
 			continue
 		}
 		valid := false
 		if len(candidates) == 0 {
 			// just waited for hit Enter
-			return
+			return 0, nil
 		}
 		for _, c := range candidates {
 			if c == i {
@@ -160,85 +227,195 @@ func userInput(candidates []int) (num int) {
 			}
 		}
 		if !valid {
-			fmt.Printf("invalid input: %s\n", input)
+			fmt.Fprintf(w, "invalid input: %s\n", input)
 			continue
 		}
 		num = i
 		break
 	}
-	return
+	return num, nil
 }
 
-type ManualStrategy struct{}
+// ManualStrategy は人間が判断する。ゲームの出力に表示し、ゲームの入力 (Game.SetInput) から読む
+type ManualStrategy struct {
+	advisor *Advisor // nilなら助言しない
+	game    *Game    // 直近に通知されたゲーム。公開処刑・疫病の判断で使う
+}
 
-func (s ManualStrategy) SelectDiscard(g *Game, p *Player) CardEvent {
-	fmt.Println(p.hand)
+// NewManualStrategy returns ManualStrategy which shows advice before each discard and sage choice if advise is true
+func NewManualStrategy(advise bool) *ManualStrategy {
+	if !advise {
+		return &ManualStrategy{}
+	}
+	return &ManualStrategy{advisor: NewAdvisor(-1)}
+}
 
-	var discard int
-	if p.hand.Has(10) {
-		discard = userInput([]int{p.hand.Another(10)})
-	} else {
-		discard = userInput(p.hand.Slice())
+func (s *ManualStrategy) Clone() PlayerStrategy {
+	c := *s
+	if s.advisor != nil {
+		c.advisor = s.advisor.clone()
 	}
+	return &c
+}
 
-	others := g.OtherPlayers(p)
-	var target *Player
-	if len(others) == 1 {
-		target = others[0]
-	} else {
-		var indices []int
-		for i, o := range others {
-			fmt.Printf("%s: [%d]\n", o.Name(), i)
-			indices = append(indices, i)
-		}
-		fmt.Println("相手は？", indices)
-		ti := userInput(indices)
-		target = others[ti]
+func (s *ManualStrategy) OnEvent(g *Game, e Event) {
+	s.game = g
+	if s.advisor == nil {
+		return
+	}
+	if s.advisor.seat < 0 {
+		s.advisor.seat = strategySeat(g, s)
+	}
+	s.advisor.OnEvent(g, e)
+}
+
+func (s *ManualStrategy) advise(v PlayerView) {
+	if s.advisor != nil {
+		s.advisor.Advise(v).Print(s.out(), v)
+	}
+}
+
+// 表示先。ゲームを知らなければ標準出力
+func (s *ManualStrategy) out() io.Writer {
+	if s.game == nil {
+		return os.Stdout
+	}
+	return s.game.output()
+}
+
+// 1行読む。ゲームを知らなければ標準入力から
+func (s *ManualStrategy) readLine(ctx context.Context) (string, error) {
+	if s.game == nil {
+		return readLine(os.Stdin)
+	}
+	return s.game.readLine(ctx)
+}
+
+func (s *ManualStrategy) input(ctx context.Context, candidates []int, undo bool) (int, error) {
+	return userInput(ctx, s.readLine, s.out(), candidates, undo)
+}
+
+// Decide asks the human for d. It returns UndoAction when the human takes back the last decision,
+// and stops waiting for the input when ctx is done.
+func (s *ManualStrategy) Decide(ctx context.Context, g *Game, d Decision) Action {
+	s.game = g
+	undo := g.CanUndo()
+	var (
+		a   Action
+		c   int
+		err error
+	)
+	switch d.Type {
+	case DecisionDiscard:
+		var e CardEvent
+		e, err = s.selectDiscard(ctx, g, g.Players[d.Seat], undo)
+		a = g.eventAction(e)
+	case DecisionWise:
+		c, err = s.selectFromWise(ctx, g, d.Candidates, undo)
+		a = NewWiseAction(c)
+	case DecisionPublicExecution:
+		c, err = s.selectOnPublicExecution(ctx, g.Players[d.Target].Hand(), undo)
+		a = NewPublicExecutionAction(c)
+	case DecisionPlague:
+		c, err = s.selectOnPlague(ctx, undo)
+		a = NewPlagueAction(c)
+	}
+	switch {
+	case err == errUndo:
+		return UndoAction
+	case err != nil:
+		// 時間切れ。この答えは使われない
+		return FirstLegalAction(g.view(g.Players[d.Seat], d))
+	}
+	return a
+}
+
+func (s *ManualStrategy) SelectDiscard(g *Game, p *Player) CardEvent {
+	e, _ := s.selectDiscard(context.Background(), g, p, false)
+	return e
+}
+
+func (s *ManualStrategy) selectDiscard(ctx context.Context, g *Game, p *Player, undo bool) (CardEvent, error) {
+	s.game = g
+	fmt.Fprintln(s.out(), p.hand)
+
+	v := g.discardView(p)
+	s.advise(v)
+	actions := LegalActions(v)
+	discard, err := s.input(ctx, discardCards(actions), undo)
+	if err != nil {
+		return CardEvent{}, err
 	}
 
 	event := CardEvent{Card: discard}
-	switch event.Card {
-	case 2:
-		event.Target = target
-		fmt.Println("捜査: 予想は？[1-10]")
-		expect := userInput([]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
-		event.Expect = expect
-	case 1, 3, 5, 6, 8, 9:
-		event.Target = target
-	case 4, 7, 10:
+	targets := discardTargets(actions, discard)
+	if len(targets) == 1 {
+		event.Target = g.Players[targets[0]]
+	} else if len(targets) > 1 {
+		for _, t := range targets {
+			fmt.Fprintf(s.out(), "%s: [%d]\n", g.Players[t].Name(), t)
+		}
+		fmt.Fprintln(s.out(), "相手は？", targets)
+		t, err := s.input(ctx, targets, undo)
+		if err != nil {
+			return CardEvent{}, err
+		}
+		event.Target = g.Players[t]
 	}
-	return event
+
+	if event.Card == 2 {
+		fmt.Fprintln(s.out(), "捜査: 予想は？[1-10]")
+		if event.Expect, err = s.input(ctx, investigationExpects(actions, g.Seat(event.Target)), undo); err != nil {
+			return CardEvent{}, err
+		}
+	}
+	return event, nil
+}
+
+func (s *ManualStrategy) SelectFromWise(g *Game, candidates []int) int {
+	c, _ := s.selectFromWise(context.Background(), g, candidates, false)
+	return c
 }
 
-func (s ManualStrategy) SelectFromWise(g *Game, candidates []int) int {
-	selected := userInput(candidates)
-	return selected
+func (s *ManualStrategy) selectFromWise(ctx context.Context, g *Game, candidates []int, undo bool) (int, error) {
+	s.game = g
+	s.advise(g.View(g.Players[g.pending.Seat]))
+	return s.input(ctx, candidates, undo)
 }
 
-func (s ManualStrategy) SelectOnPublicExecution(player, target *Player, hand Hand) (discard int) {
+func (s *ManualStrategy) SelectOnPublicExecution(player, target *Player, hand Hand) int {
+	c, _ := s.selectOnPublicExecution(context.Background(), hand, false)
+	return c
+}
+
+func (s *ManualStrategy) selectOnPublicExecution(ctx context.Context, hand Hand, undo bool) (int, error) {
 	// 可視
-	fmt.Printf("相手のカード: %s", hand)
-	fmt.Println("捨てるカードは？")
-	discard = userInput(hand.Slice())
-	return discard
+	fmt.Fprintf(s.out(), "相手のカード: %s", hand)
+	fmt.Fprintln(s.out(), "捨てるカードは？")
+	return s.input(ctx, hand.Slice(), undo)
+}
+
+func (s *ManualStrategy) SelectOnPlague(player, target *Player, hand Hand) int {
+	i, _ := s.selectOnPlague(context.Background(), false)
+	return hand.At(i)
 }
 
-func (s ManualStrategy) SelectOnPlague(player, target *Player, hand Hand) (discard int) {
+// 捨てさせる位置を返す
+func (s *ManualStrategy) selectOnPlague(ctx context.Context, undo bool) (int, error) {
 	// 不可視
-	fmt.Println("捨てるカードは？ 左:[0], 右[1]")
-	discardIdx := userInput([]int{0, 1})
-	return hand.At(discardIdx)
+	fmt.Fprintln(s.out(), "捨てるカードは？ 左:[0], 右[1]")
+	return s.input(ctx, []int{0, 1}, undo)
 }
 
-func (s ManualStrategy) KnowByClairvoyance(g *Game, player, target *Player, c int) {
-	fmt.Printf("%sの手札: [%d]\n", target.Name(), c)
-	fmt.Println("put any char")
-	input := make([]byte, 1)
-	os.Stdin.Read(input)
+func (s *ManualStrategy) KnowByClairvoyance(g *Game, player, target *Player, c int) {
+	s.game = g
+	fmt.Fprintf(s.out(), "%sの手札: [%d]\n", target.Name(), c)
+	fmt.Fprintln(s.out(), "put any char")
+	g.waitRead(player)
 }
 
-func (s ManualStrategy) OnOpponentEvent(g *Game, player, opponent *Player, e CardEvent) {
-	fmt.Println("put any char")
-	input := make([]byte, 1)
-	os.Stdin.Read(input)
+func (s *ManualStrategy) OnOpponentEvent(g *Game, player, opponent *Player, e CardEvent) {
+	s.game = g
+	fmt.Fprintln(s.out(), "put any char")
+	g.waitRead(player)
 }
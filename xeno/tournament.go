@@ -0,0 +1,359 @@
+package xeno
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// TournamentConfig は Tournament の設定
+type TournamentConfig struct {
+	Specs   []string // 参加する戦略の指定 (NewStrategy)
+	Players []int    // 1ゲームの人数。空なら2人
+	Swiss   bool     // falseなら総当たり
+	Rounds  int      // 総当たりの繰り返し・スイス式の回戦数。0なら1
+	Games   int      // 1つの席順で対戦する回数。0なら1
+	Seed    int64
+}
+
+// LeaderboardVersion is the format version of Leaderboard files
+const LeaderboardVersion = 1
+
+const (
+	initialElo = 1500
+	eloK       = 16
+)
+
+// Rating は1つの戦略の成績
+type Rating struct {
+	Spec  string  `json:"spec"`
+	Elo   float64 `json:"elo"`
+	Games int     `json:"games"`
+	Wins  int     `json:"wins"`
+	Draws int     `json:"draws"` // 勝者がいないゲーム
+}
+
+func (r *Rating) WinRate() float64 {
+	if r.Games == 0 {
+		return 0
+	}
+	return float64(r.Wins) / float64(r.Games)
+}
+
+// PairResult は2つの戦略が同じゲームにいたときの結果
+type PairResult struct {
+	A     string `json:"a"`
+	B     string `json:"b"`
+	AWins int    `json:"a_wins"`
+	BWins int    `json:"b_wins"`
+	Other int    `json:"other"` // どちらも勝たなかったゲーム
+}
+
+// Leaderboard はトーナメントの結果。保存して次の実行で続きから更新できる
+type Leaderboard struct {
+	Version int                    `json:"version"`
+	Games   int                    `json:"games"`
+	Ratings map[string]*Rating     `json:"ratings"`
+	Pairs   map[string]*PairResult `json:"pairs"`
+}
+
+func NewLeaderboard() *Leaderboard {
+	return &Leaderboard{
+		Version: LeaderboardVersion,
+		Ratings: map[string]*Rating{},
+		Pairs:   map[string]*PairResult{},
+	}
+}
+
+// LoadLeaderboard reads a leaderboard written by Leaderboard.Save
+func LoadLeaderboard(r io.Reader) (*Leaderboard, error) {
+	lb := NewLeaderboard()
+	if err := json.NewDecoder(r).Decode(lb); err != nil {
+		return nil, err
+	}
+	if lb.Version != LeaderboardVersion {
+		return nil, fmt.Errorf("unsupported leaderboard version: %d", lb.Version)
+	}
+	return lb, nil
+}
+
+func (lb *Leaderboard) Save(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(lb)
+}
+
+func (lb *Leaderboard) rating(spec string) *Rating {
+	r, ok := lb.Ratings[spec]
+	if !ok {
+		r = &Rating{Spec: spec, Elo: initialElo}
+		lb.Ratings[spec] = r
+	}
+	return r
+}
+
+func (lb *Leaderboard) pair(a, b string) (*PairResult, bool) {
+	swapped := a > b
+	if swapped {
+		a, b = b, a
+	}
+	key := a + "\t" + b
+	p, ok := lb.Pairs[key]
+	if !ok {
+		p = &PairResult{A: a, B: b}
+		lb.Pairs[key] = p
+	}
+	return p, swapped
+}
+
+// Record adds the result of a game. specs are in seat order and winner is the seat of the winner or -1.
+// Eloは勝者が他の全員に勝ったとして2人ずつ更新する。勝者がいなければ全員引き分けとする
+func (lb *Leaderboard) Record(specs []string, winner int) {
+	lb.Games++
+	for i, spec := range specs {
+		r := lb.rating(spec)
+		r.Games++
+		if i == winner {
+			r.Wins++
+		} else if winner < 0 {
+			r.Draws++
+		}
+	}
+
+	k := eloK / float64(len(specs)-1)
+	delta := make([]float64, len(specs))
+	for i := range specs {
+		for j := i + 1; j < len(specs); j++ {
+			if specs[i] == specs[j] {
+				continue
+			}
+			p, swapped := lb.pair(specs[i], specs[j])
+			a, b := i, j
+			if swapped {
+				a, b = j, i
+			}
+			switch winner {
+			case a:
+				p.AWins++
+			case b:
+				p.BWins++
+			default:
+				p.Other++
+			}
+
+			score := 0.5
+			switch winner {
+			case i:
+				score = 1
+			case j:
+				score = 0
+			default:
+				if winner >= 0 {
+					// 負け同士は比べない
+					continue
+				}
+			}
+			ri, rj := lb.rating(specs[i]), lb.rating(specs[j])
+			expected := 1 / (1 + math.Pow(10, (rj.Elo-ri.Elo)/400))
+			delta[i] += k * (score - expected)
+			delta[j] -= k * (score - expected)
+		}
+	}
+	for i, spec := range specs {
+		lb.rating(spec).Elo += delta[i]
+	}
+}
+
+// Standings returns ratings sorted by Elo
+func (lb *Leaderboard) Standings() []*Rating {
+	var rs []*Rating
+	for _, r := range lb.Ratings {
+		rs = append(rs, r)
+	}
+	sort.Slice(rs, func(i, j int) bool {
+		if rs[i].Elo != rs[j].Elo {
+			return rs[i].Elo > rs[j].Elo
+		}
+		return rs[i].Spec < rs[j].Spec
+	})
+	return rs
+}
+
+// Print writes the standings and the pairwise results
+func (lb *Leaderboard) Print(w io.Writer) {
+	fmt.Fprintf(w, "%-4s %-30s %7s %6s %6s %6s\n", "#", "strategy", "elo", "games", "wins", "rate")
+	for i, r := range lb.Standings() {
+		fmt.Fprintf(w, "%-4d %-30s %7.1f %6d %6d %6.3f\n", i+1, r.Spec, r.Elo, r.Games, r.Wins, r.WinRate())
+	}
+
+	var keys []string
+	for k := range lb.Pairs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if len(keys) > 0 {
+		fmt.Fprintln(w)
+	}
+	for _, k := range keys {
+		p := lb.Pairs[k]
+		fmt.Fprintf(w, "%s vs %s: %d-%d (%d)\n", p.A, p.B, p.AWins, p.BWins, p.Other)
+	}
+}
+
+// Tournament は戦略同士を対戦させてLeaderboardを更新する
+type Tournament struct {
+	conf TournamentConfig
+	rng  *rand.Rand
+	// Progress is called after each game, if not nil
+	Progress func(game, total int)
+	// Profiles records each game to the profiles keyed by the strategy specs, if not nil
+	Profiles *Profiles
+}
+
+func NewTournament(conf TournamentConfig) (*Tournament, error) {
+	if len(conf.Players) == 0 {
+		conf.Players = []int{2}
+	}
+	if conf.Rounds <= 0 {
+		conf.Rounds = 1
+	}
+	if conf.Games <= 0 {
+		conf.Games = 1
+	}
+	if len(conf.Specs) < 2 {
+		return nil, fmt.Errorf("at least 2 strategies are required")
+	}
+	for _, n := range conf.Players {
+		if n < 2 || n > len(conf.Specs) {
+			return nil, fmt.Errorf("invalid number of players: %d", n)
+		}
+	}
+	for _, spec := range conf.Specs {
+		if err := checkStrategy(spec); err != nil {
+			return nil, err
+		}
+	}
+	return &Tournament{conf: conf, rng: rand.New(rand.NewSource(conf.Seed))}, nil
+}
+
+// Run plays the tournament and records the results to lb
+func (t *Tournament) Run(lb *Leaderboard) {
+	total := 0
+	for round := 0; round < t.conf.Rounds; round++ {
+		var tables [][]string
+		for _, n := range t.conf.Players {
+			if t.conf.Swiss {
+				tables = append(tables, swissTables(t.conf.Specs, n, lb, t.rng)...)
+			} else {
+				tables = append(tables, combinations(t.conf.Specs, n)...)
+			}
+		}
+		var games [][]string
+		for _, table := range tables {
+			for _, seating := range permutationsOf(table) {
+				for i := 0; i < t.conf.Games; i++ {
+					games = append(games, seating)
+				}
+			}
+		}
+		for _, specs := range games {
+			lb.Record(specs, t.play(specs))
+			total++
+			if t.Progress != nil {
+				t.Progress(total, len(games)*t.conf.Rounds)
+			}
+		}
+	}
+}
+
+// 1ゲーム対戦して勝者の席を返す。勝者がいなければ-1
+func (t *Tournament) play(specs []string) int {
+	g := newSeededGame(len(specs), t.rng)
+	for i, spec := range specs {
+		s, err := NewSeededStrategy(spec, t.rng)
+		if err != nil {
+			log.Fatalf("Tournament: %v", err)
+		}
+		g.Players[i].strategy = s
+	}
+	var r *Record
+	if t.Profiles != nil {
+		for i, spec := range specs {
+			g.Players[i].name = spec
+		}
+		r = g.StartRecording()
+	}
+	g.Loop()
+	closeStrategies(g)
+	if r != nil {
+		if err := t.Profiles.Add(specs, r); err != nil {
+			log.Fatalf("Tournament: %v", err)
+		}
+	}
+	winners := g.Winners()
+	if len(winners) != 1 {
+		return -1
+	}
+	return g.Seat(winners[0])
+}
+
+// スイス式。レーティング順に並べて上からn人ずつ組む。余りは上位から補う
+func swissTables(specs []string, n int, lb *Leaderboard, rng *rand.Rand) [][]string {
+	sorted := append([]string{}, specs...)
+	rng.Shuffle(len(sorted), func(i, j int) {
+		sorted[i], sorted[j] = sorted[j], sorted[i]
+	})
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return lb.rating(sorted[i]).Elo > lb.rating(sorted[j]).Elo
+	})
+	var tables [][]string
+	for i := 0; i < len(sorted); i += n {
+		if i+n <= len(sorted) {
+			tables = append(tables, sorted[i:i+n])
+			continue
+		}
+		table := append([]string{}, sorted[i:]...)
+		table = append(table, sorted[:n-len(table)]...)
+		tables = append(tables, table)
+	}
+	return tables
+}
+
+// n個の組み合わせ
+func combinations(items []string, n int) [][]string {
+	var result [][]string
+	var rec func(start int, cur []string)
+	rec = func(start int, cur []string) {
+		if len(cur) == n {
+			result = append(result, append([]string{}, cur...))
+			return
+		}
+		for i := start; i < len(items); i++ {
+			rec(i+1, append(cur, items[i]))
+		}
+	}
+	rec(0, nil)
+	return result
+}
+
+// 全ての席順
+func permutationsOf(items []string) [][]string {
+	var result [][]string
+	var rec func(cur, rest []string)
+	rec = func(cur, rest []string) {
+		if len(rest) == 0 {
+			result = append(result, append([]string{}, cur...))
+			return
+		}
+		for i := range rest {
+			next := append(append([]string{}, rest[:i]...), rest[i+1:]...)
+			rec(append(cur, rest[i]), next)
+		}
+	}
+	rec(nil, items)
+	return result
+}
@@ -0,0 +1,87 @@
+package xeno
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestLeaderboard_Record(t *testing.T) {
+	lb := NewLeaderboard()
+	lb.Record([]string{"a", "b", "c"}, 1)
+
+	a, b, c := lb.Ratings["a"], lb.Ratings["b"], lb.Ratings["c"]
+	if b.Elo <= initialElo || a.Elo >= initialElo || a.Elo != c.Elo {
+		t.Errorf("unexpected ratings: %v %v %v", a, b, c)
+	}
+	if sum := a.Elo + b.Elo + c.Elo; math.Abs(sum-3*initialElo) > 1e-9 {
+		t.Errorf("ratings should be conserved: %v", sum)
+	}
+	if p := lb.Pairs["a\tb"]; p.BWins != 1 || p.AWins != 0 {
+		t.Errorf("unexpected pair: %v", p)
+	}
+	if p := lb.Pairs["a\tc"]; p.Other != 1 {
+		t.Errorf("unexpected pair: %v", p)
+	}
+
+	lb.Record([]string{"a", "c"}, -1)
+	if lb.Ratings["a"].Draws != 1 || lb.Games != 2 {
+		t.Errorf("unexpected draws: %v", lb.Ratings["a"])
+	}
+}
+
+func TestTournament_Run(t *testing.T) {
+	tr, err := NewTournament(TournamentConfig{
+		Specs:   []string{"com", "random", "expert"},
+		Players: []int{2, 3},
+		Games:   2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lb := NewLeaderboard()
+	tr.Run(lb)
+	// 2人: 3組×2席順×2回、3人: 1組×6席順×2回
+	if lb.Games != 24 {
+		t.Errorf("want: 24 games, got: %d", lb.Games)
+	}
+
+	var buf bytes.Buffer
+	if err := lb.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadLeaderboard(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lb, loaded) {
+		t.Errorf("loaded leaderboard differs")
+	}
+
+	// 続きから更新できる
+	tr, err = NewTournament(TournamentConfig{Specs: []string{"com", "random", "expert"}, Swiss: true, Rounds: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.Run(loaded)
+	if loaded.Games <= 24 {
+		t.Errorf("want: more games, got: %d", loaded.Games)
+	}
+
+	if _, err := NewTournament(TournamentConfig{Specs: []string{"com", "unknown"}}); err == nil {
+		t.Errorf("want: error")
+	}
+	if _, err := NewTournament(TournamentConfig{Specs: []string{"com", "random"}, Players: []int{3}}); err == nil {
+		t.Errorf("want: error")
+	}
+}
+
+func TestCombinations(t *testing.T) {
+	if got := combinations([]string{"a", "b", "c"}, 2); !reflect.DeepEqual(got, [][]string{{"a", "b"}, {"a", "c"}, {"b", "c"}}) {
+		t.Errorf("unexpected combinations: %v", got)
+	}
+	if got := permutationsOf([]string{"a", "b", "c"}); len(got) != 6 {
+		t.Errorf("unexpected permutations: %v", got)
+	}
+}
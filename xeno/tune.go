@@ -0,0 +1,289 @@
+package xeno
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ParamFileVersion is the format version of ParamFile
+const ParamFileVersion = 1
+
+// ParamFile は調整した戦略の引数。Specで戦略の指定になる
+type ParamFile struct {
+	Version    int                `json:"version"`
+	Strategy   string             `json:"strategy"`
+	Params     map[string]float64 `json:"params"`
+	Fitness    float64            `json:"fitness"`
+	Generation int                `json:"generation"`
+}
+
+// Spec returns the strategy spec for NewStrategy
+func (f *ParamFile) Spec() string {
+	var keys []string
+	for k := range f.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var args []string
+	for _, k := range keys {
+		args = append(args, k+"="+strconv.FormatFloat(f.Params[k], 'g', -1, 64))
+	}
+	if len(args) == 0 {
+		return f.Strategy
+	}
+	return f.Strategy + ":" + strings.Join(args, ",")
+}
+
+func (f *ParamFile) Save(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(f)
+}
+
+// LoadParamFile reads a file written by ParamFile.Save
+func LoadParamFile(r io.Reader) (*ParamFile, error) {
+	var f ParamFile
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, err
+	}
+	if f.Version != ParamFileVersion {
+		return nil, fmt.Errorf("unsupported param file version: %d", f.Version)
+	}
+	return &f, nil
+}
+
+func loadParamFile(path string) (*ParamFile, error) {
+	fp, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	return LoadParamFile(fp)
+}
+
+// 調整できる戦略の引数と初期値
+var tunables = map[string]func() map[string]float64{
+	"expert": func() map[string]float64 {
+		conf := ExpertPresets["normal"]
+		params := map[string]float64{}
+		for k, w := range conf.weights() {
+			params[k] = *w
+		}
+		return params
+	},
+}
+
+// TuneConfig は Tune の設定
+type TuneConfig struct {
+	Strategy    string   // 調整する戦略の名前。空なら"expert"
+	Pool        []string // 対戦相手の戦略の指定。空なら"com"
+	Population  int      // 0なら16
+	Generations int      // 0なら10
+	Games       int      // 1つの引数の組の対戦数。0なら20
+	Mutation    float64  // 突然変異の大きさ (値に対する標準偏差の比)。0なら0.2
+	Elite       int      // そのまま次の世代に残す数。0なら2
+	Parallel    int      // 並行して評価する数。0ならCPUの数
+	Seed        int64
+}
+
+func (c *TuneConfig) defaults() {
+	if c.Strategy == "" {
+		c.Strategy = "expert"
+	}
+	if len(c.Pool) == 0 {
+		c.Pool = []string{"com"}
+	}
+	if c.Population <= 0 {
+		c.Population = 16
+	}
+	if c.Generations <= 0 {
+		c.Generations = 10
+	}
+	if c.Games <= 0 {
+		c.Games = 20
+	}
+	if c.Mutation <= 0 {
+		c.Mutation = 0.2
+	}
+	if c.Elite <= 0 {
+		c.Elite = 2
+	}
+	if c.Elite > c.Population {
+		c.Elite = c.Population
+	}
+	if c.Parallel <= 0 {
+		c.Parallel = runtime.NumCPU()
+	}
+}
+
+type individual struct {
+	params  map[string]float64
+	fitness float64
+}
+
+// Tune evolves parameters of conf.Strategy by playing two-player games against conf.Pool.
+// All individuals of a generation play the same deals. The result is deterministic under conf.Seed.
+// progress is called after each generation, if not nil.
+func Tune(conf TuneConfig, progress func(best *ParamFile)) (*ParamFile, error) {
+	conf.defaults()
+	initial, ok := tunables[conf.Strategy]
+	if !ok {
+		return nil, fmt.Errorf("strategy %s is not tunable", conf.Strategy)
+	}
+	for _, spec := range conf.Pool {
+		if err := checkStrategy(spec); err != nil {
+			return nil, err
+		}
+	}
+	rng := rand.New(rand.NewSource(conf.Seed))
+
+	var keys []string
+	for k := range initial() {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	population := []*individual{{params: initial()}}
+	for len(population) < conf.Population {
+		population = append(population, &individual{params: mutate(initial(), keys, conf.Mutation, rng)})
+	}
+
+	var best *ParamFile
+	for gen := 1; gen <= conf.Generations; gen++ {
+		seeds := make([]int64, conf.Games)
+		for i := range seeds {
+			seeds[i] = rng.Int63()
+		}
+		if err := evaluate(conf, population, seeds); err != nil {
+			return nil, err
+		}
+		sort.SliceStable(population, func(i, j int) bool {
+			return population[i].fitness > population[j].fitness
+		})
+
+		top := population[0]
+		best = &ParamFile{
+			Version:    ParamFileVersion,
+			Strategy:   conf.Strategy,
+			Params:     copyParams(top.params),
+			Fitness:    top.fitness,
+			Generation: gen,
+		}
+		if progress != nil {
+			progress(best)
+		}
+		if gen == conf.Generations {
+			break
+		}
+
+		next := make([]*individual, 0, conf.Population)
+		for _, e := range population[:conf.Elite] {
+			next = append(next, &individual{params: copyParams(e.params)})
+		}
+		for len(next) < conf.Population {
+			a, b := selectParent(population, rng), selectParent(population, rng)
+			child := map[string]float64{}
+			for _, k := range keys {
+				if rng.Intn(2) == 0 {
+					child[k] = a.params[k]
+				} else {
+					child[k] = b.params[k]
+				}
+			}
+			next = append(next, &individual{params: mutate(child, keys, conf.Mutation, rng)})
+		}
+		population = next
+	}
+	return best, nil
+}
+
+// 2つ選んで良い方を親にする
+func selectParent(population []*individual, rng *rand.Rand) *individual {
+	a, b := population[rng.Intn(len(population))], population[rng.Intn(len(population))]
+	if b.fitness > a.fitness {
+		return b
+	}
+	return a
+}
+
+func mutate(params map[string]float64, keys []string, scale float64, rng *rand.Rand) map[string]float64 {
+	for _, k := range keys {
+		v := params[k]
+		params[k] = v + rng.NormFloat64()*scale*math.Max(math.Abs(v), 0.1)
+	}
+	return params
+}
+
+func copyParams(params map[string]float64) map[string]float64 {
+	c := make(map[string]float64, len(params))
+	for k, v := range params {
+		c[k] = v
+	}
+	return c
+}
+
+// 全員の勝率を求める。引き分けは0.5勝とする。戦略が作れなければ最初のエラーを返す
+func evaluate(conf TuneConfig, population []*individual, seeds []int64) error {
+	jobs := make(chan *individual)
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		ferr error
+	)
+	for w := 0; w < conf.Parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ind := range jobs {
+				fitness, err := evaluateOne(conf, ind, seeds)
+				if err != nil {
+					mu.Lock()
+					if ferr == nil {
+						ferr = err
+					}
+					mu.Unlock()
+					continue
+				}
+				ind.fitness = fitness
+			}
+		}()
+	}
+	for _, ind := range population {
+		jobs <- ind
+	}
+	close(jobs)
+	wg.Wait()
+	return ferr
+}
+
+func evaluateOne(conf TuneConfig, ind *individual, seeds []int64) (float64, error) {
+	spec := (&ParamFile{Strategy: conf.Strategy, Params: ind.params}).Spec()
+	score := 0.0
+	for i, seed := range seeds {
+		rng := rand.New(rand.NewSource(seed))
+		opponent := conf.Pool[(i/2)%len(conf.Pool)]
+		seat := i % 2
+		g := newSeededGame(2, rng)
+		var err error
+		if g.Players[seat].strategy, err = NewSeededStrategy(spec, rng); err != nil {
+			return 0, err
+		}
+		if g.Players[1-seat].strategy, err = NewSeededStrategy(opponent, rng); err != nil {
+			closeStrategies(g)
+			return 0, err
+		}
+		g.Loop()
+		closeStrategies(g)
+		score += (cfrUtility(g, seat) + 1) / 2
+	}
+	return score / float64(len(seeds)), nil
+}
@@ -0,0 +1,114 @@
+package xeno
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTune(t *testing.T) {
+	conf := TuneConfig{Pool: []string{"random"}, Population: 4, Generations: 2, Games: 4, Seed: 1}
+	gens := 0
+	a, err := Tune(conf, func(f *ParamFile) {
+		gens++
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gens != 2 || a.Generation != 2 {
+		t.Errorf("want: 2 generations, got: %d %d", gens, a.Generation)
+	}
+	if len(a.Params) != len(tunables["expert"]()) {
+		t.Errorf("want: all weights, got: %v", a.Params)
+	}
+	conf.Parallel = 1
+	b, err := Tune(conf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("not deterministic: %v %v", a, b)
+	}
+
+	if _, err := Tune(TuneConfig{Strategy: "random"}, nil); err == nil {
+		t.Error("want: error for a strategy without parameters")
+	}
+	if _, err := Tune(TuneConfig{Pool: []string{"unknown"}}, nil); err == nil {
+		t.Error("want: error for an unknown opponent")
+	}
+
+	// 確認のときだけ作れる相手のエラーも返す
+	var built int32
+	RegisterStrategy("test-tune-once", func(p StrategyParams, rng *rand.Rand) (PlayerStrategy, error) {
+		if atomic.AddInt32(&built, 1) > 1 {
+			return nil, errors.New("test-tune-once: built twice")
+		}
+		return RandomStrategy{rng}, p.check()
+	})
+	if _, err := Tune(TuneConfig{Pool: []string{"test-tune-once"}, Population: 2, Generations: 1, Games: 2}, nil); err == nil {
+		t.Error("want: error from the second build")
+	}
+}
+
+func TestParamFile(t *testing.T) {
+	f := &ParamFile{Version: ParamFileVersion, Strategy: "expert", Params: map[string]float64{"keep": 0.5, "boy": 2}}
+	if got, want := f.Spec(), "expert:boy=2,keep=0.5"; got != want {
+		t.Errorf("want: %s, got: %s", want, got)
+	}
+
+	var buf bytes.Buffer
+	if err := f.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "xeno")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "params.json")
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := LoadParamFile(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(f, g) {
+		t.Errorf("want: %v, got: %v", f, g)
+	}
+
+	s, err := NewStrategy("file:" + path + ",seed=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := s.(*ExpertStrategy); !ok || e.conf.Keep != 0.5 || e.conf.Boy != 2 {
+		t.Errorf("want: expert with the params, got: %#v", s)
+	}
+
+	game, err := BuildGame(GameConfig{ParamFile: path, Players: []PlayerConfig{{}, {StrategyName: "random"}, {Manual: true}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := game.Players[0].strategy.(*ExpertStrategy); !ok {
+		t.Errorf("want: expert, got: %T", game.Players[0].strategy)
+	}
+	if _, ok := game.Players[1].strategy.(RandomStrategy); !ok {
+		t.Errorf("want: random, got: %T", game.Players[1].strategy)
+	}
+	if _, ok := game.Players[2].strategy.(*ManualStrategy); !ok {
+		t.Errorf("want: manual, got: %T", game.Players[2].strategy)
+	}
+
+	buf.Reset()
+	buf.WriteString(`{"version": 99}`)
+	if _, err := LoadParamFile(&buf); err == nil {
+		t.Error("want: version error")
+	}
+}
@@ -0,0 +1,86 @@
+package xeno
+
+import "context"
+
+// 人間の判断の前のゲームの状態
+type snapshot struct {
+	game     *Game
+	moves    int // 記録の長さ
+	shuffles int
+}
+
+// UndoAction is returned by human strategies instead of an action to take back the last decision of the player.
+// When undo is disabled or there is nothing to take back, the decision is asked again.
+const UndoAction Action = -1
+
+// UndoEnabled reports whether humans can take back their decisions
+func (g *Game) UndoEnabled() bool {
+	return !g.noUndo
+}
+
+// CanUndo reports whether the human deciding now can take back the last decision
+func (g *Game) CanUndo() bool {
+	return !g.noUndo && len(g.snapshots) >= 2
+}
+
+func isHuman(p *Player) bool {
+	if p.manual {
+		return true
+	}
+	_, ok := p.strategy.(*ManualStrategy)
+	return ok
+}
+
+// 人間の判断の前に状態を残す
+func (g *Game) saveSnapshot(p *Player) {
+	if g.noUndo || !isHuman(p) {
+		return
+	}
+	s := snapshot{game: g.Clone()}
+	if g.record != nil {
+		s.moves, s.shuffles = len(g.record.Moves), len(g.record.Shuffles)
+	}
+	g.snapshots = append(g.snapshots, s)
+}
+
+// 判断を聞く。待ったされたらfalse
+func (g *Game) askUndoable(ctx context.Context, d Decision) (Action, bool) {
+	a := g.ask(ctx, d)
+	return a, a != UndoAction
+}
+
+// 今の判断と、その前の人間の判断を取り消す。取り消す判断がなければfalseで、今の判断を聞き直す
+func (g *Game) undo() bool {
+	if g.noUndo || len(g.snapshots) < 2 {
+		if len(g.snapshots) > 0 {
+			// 聞き直すときにまた残すので捨てる
+			g.snapshots = g.snapshots[:len(g.snapshots)-1]
+		}
+		return false
+	}
+	// 最後のものは今の判断の前。その1つ前まで戻して、同じ判断をもう一度聞く
+	s := g.snapshots[len(g.snapshots)-2]
+	g.snapshots = g.snapshots[:len(g.snapshots)-2]
+
+	restored := s.game.Clone()
+	restored.out = g.out
+	restored.in = g.in
+	restored.line = g.line
+	restored.ctx = g.ctx
+	restored.hotSeat = g.hotSeat
+	restored.observers = g.observers
+	restored.record = g.record
+	restored.noUndo = g.noUndo
+	restored.snapshots = g.snapshots
+	restored.auditCards = g.auditCards
+	restored.auditErr = g.auditErr
+	restored.profileFile = g.profileFile
+	restored.profileKeys = g.profileKeys
+	if g.record != nil {
+		g.record.Moves = g.record.Moves[:s.moves]
+		g.record.Shuffles = g.record.Shuffles[:s.shuffles]
+	}
+	*g = *restored
+	g.println("待った: 前の判断に戻ります")
+	return true
+}
@@ -0,0 +1,145 @@
+package xeno
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 決めた回で待ったする人間
+type undoingStrategy struct {
+	RandomStrategy
+	undoAt int      // 何回目の判断で待ったするか
+	asked  int      // 聞かれた回数
+	states []string // 聞かれたときのターンと手札
+	undo   []bool   // 聞かれたときに待ったできたか
+}
+
+func (s *undoingStrategy) Decide(ctx context.Context, g *Game, d Decision) Action {
+	s.asked++
+	s.states = append(s.states, fmt.Sprintf("%d %v", g.Turn(), g.Players[d.Seat].Hand()))
+	s.undo = append(s.undo, g.CanUndo())
+	if s.asked == s.undoAt {
+		return UndoAction
+	}
+	return askStrategy(ctx, g, s.RandomStrategy, d)
+}
+
+func newUndoGame(undoAt int, disabled bool) (*Game, *undoingStrategy) {
+	g := NewGame(GameConfig{Players: []PlayerConfig{{}, {}}, DisableUndo: disabled})
+	g.SetOutput(ioutil.Discard)
+	human := &undoingStrategy{undoAt: undoAt}
+	g.Players[0].strategy, g.Players[0].manual = human, true
+	g.Players[1].strategy = RandomStrategy{}
+	return g, human
+}
+
+func TestGame_Undo(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		disabled := i%2 == 1
+		g, human := newUndoGame(3, disabled)
+		r := g.StartRecording()
+		g.Loop()
+		if human.asked < 4 {
+			// 待ったする前に終わった
+			continue
+		}
+		// 待ったすると2回目の判断からやり直す。禁止なら3回目を聞き直す
+		want := 1
+		if disabled {
+			want = 2
+		}
+		if human.states[3] != human.states[want] {
+			t.Errorf("disabled %v: want: %s, got: %s", disabled, human.states[want], human.states[3])
+		}
+		if disabled != !g.UndoEnabled() {
+			t.Errorf("UndoEnabled: want: %v", !disabled)
+		}
+		// 最初の判断は戻す判断がない
+		if human.undo[0] || human.undo[1] != !disabled {
+			t.Errorf("disabled %v: CanUndo: %v", disabled, human.undo)
+		}
+
+		// 取り消した判断は記録に残らない
+		replayed, err := r.Replay(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, p := range g.Players {
+			if !reflect.DeepEqual(p.Discarded(), replayed.Players[i].Discarded()) {
+				t.Errorf("disabled %v: replay differs: %v %v", disabled, p, replayed.Players[i])
+			}
+		}
+	}
+}
+
+func TestGame_Undo_First(t *testing.T) {
+	g, human := newUndoGame(1, false)
+	g.Loop()
+	// 戻す判断がないので同じ判断を聞き直す
+	if human.states[0] != human.states[1] {
+		t.Errorf("want: %s, got: %s", human.states[0], human.states[1])
+	}
+	if len(g.snapshots) != human.asked-1 {
+		t.Errorf("want: %d snapshots, got: %d", human.asked-1, len(g.snapshots))
+	}
+}
+
+func TestManualStrategy_UndoPrompt(t *testing.T) {
+	for _, disabled := range []bool{false, true} {
+		undo := 0
+		// 1つの判断で何度も聞くことも、2回目の判断の前に終わることもあるので何度か打つ
+		for i := 0; i < 20; i++ {
+			g := NewGame(GameConfig{Players: []PlayerConfig{{Manual: true}, {Manual: true}}, DisableUndo: disabled})
+			var out bytes.Buffer
+			g.SetOutput(&out)
+			g.SetInput(manualInput())
+			g.Loop()
+
+			var prompts []string
+			for _, line := range strings.Split(out.String(), "\n") {
+				if strings.HasPrefix(line, "Select ") {
+					prompts = append(prompts, line)
+				}
+			}
+			if len(prompts) > 0 && strings.Contains(prompts[0], "(u: undo)") {
+				t.Errorf("disabled %v: first prompt shows undo: %s", disabled, prompts[0])
+			}
+			for _, p := range prompts {
+				if strings.Contains(p, "(u: undo)") {
+					undo++
+				}
+			}
+		}
+		if (undo > 0) == disabled {
+			t.Errorf("disabled %v: %d prompts show undo", disabled, undo)
+		}
+	}
+}
+
+func TestManualStrategy_Undo(t *testing.T) {
+	var cycle string
+	for i := 0; i <= 10; i++ {
+		cycle += fmt.Sprintf("%d\nu\n", i)
+	}
+	// 最初の判断で終わるゲームもあるので何度か打つ
+	undone := false
+	for i := 0; i < 20 && !undone; i++ {
+		g := NewGame(GameConfig{Players: []PlayerConfig{{Manual: true}, {Manual: true}}})
+		var out bytes.Buffer
+		g.SetOutput(&out)
+		g.SetInput(strings.NewReader(strings.Repeat(cycle, 100)))
+		g.Loop()
+		if !g.Over() {
+			t.Fatal("game is not over")
+		}
+		undone = strings.Contains(out.String(), "待った: 前の判断に戻ります")
+	}
+	if !undone {
+		t.Error("want: undo by u")
+	}
+}
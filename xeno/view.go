@@ -0,0 +1,147 @@
+package xeno
+
+// PublicPlayer はプレイヤーについて全員が知っている情報
+type PublicPlayer struct {
+	Name       string
+	HandCount  int
+	Discarded  []int
+	Dropped    bool
+	Protected  bool
+	CalledWise bool
+}
+
+// PlayerView は1人のプレイヤーから見えるゲームの状態
+type PlayerView struct {
+	Seat          int // 視点のプレイヤーの席
+	Turn          int
+	Hand          []int
+	Known         map[int]int // 透視などで知っている相手の手札 (席→カード)
+	Players       []PublicPlayer
+	DeckCount     int
+	Reincarnation bool // 転生札が残っているか
+	BoyAppeared   bool
+
+	// 判断待ちの内容。視点のプレイヤーの判断でなければDecisionNone
+	Decision   DecisionType
+	Target     int   // 公開処刑・疫病の対象の席。なければ-1
+	Trigger    int   // 公開処刑・疫病を発動したカード
+	Candidates []int // 賢者の候補、公開処刑で見える対象の手札
+}
+
+// Targets returns seats which can be targeted by the viewer
+func (v PlayerView) Targets() []int {
+	var targets []int
+	for i, p := range v.Players {
+		if i != v.Seat && !p.Dropped {
+			targets = append(targets, i)
+		}
+	}
+	return targets
+}
+
+// View returns what p can see, including the decision p has to make now
+func (g *Game) View(p *Player) PlayerView {
+	return g.view(p, g.pending)
+}
+
+func (g *Game) view(p *Player, d Decision) PlayerView {
+	seat := g.Seat(p)
+	v := PlayerView{
+		Seat:        seat,
+		Turn:        g.turn,
+		Hand:        append([]int{}, p.Hand().Slice()...),
+		Known:       map[int]int{},
+		Players:     make([]PublicPlayer, len(g.Players)),
+		BoyAppeared: g.boyAppeared,
+		Target:      -1,
+	}
+	if g.Deck != nil {
+		v.DeckCount = g.Deck.count()
+		v.Reincarnation = g.Deck.reincCard != 0
+	}
+	for i, op := range g.Players {
+		v.Players[i] = PublicPlayer{
+			Name:       op.Name(),
+			HandCount:  op.Hand().Count(),
+			Discarded:  op.Discarded(),
+			Dropped:    op.Dropped(),
+			Protected:  op.Protected(),
+			CalledWise: op.CalledWise(),
+		}
+		if c, ok := p.known[op.ID()]; ok && op != p && !op.Dropped() {
+			v.Known[i] = c
+		}
+	}
+
+	if d.Type == DecisionNone || d.Seat != seat {
+		return v
+	}
+	v.Decision = d.Type
+	switch d.Type {
+	case DecisionWise, DecisionPublicExecution:
+		v.Candidates = append([]int{}, d.Candidates...)
+	}
+	if d.Type == DecisionPublicExecution || d.Type == DecisionPlague {
+		v.Target = d.Target
+		v.Trigger = d.Trigger
+	}
+	return v
+}
+
+// Seat returns index of p in g.Players
+func (g Game) Seat(p *Player) int {
+	if p == nil {
+		return -1
+	}
+	for i, op := range g.Players {
+		if op == p {
+			return i
+		}
+	}
+	for i, op := range g.Players {
+		if op.ID() == p.ID() {
+			return i
+		}
+	}
+	return -1
+}
+
+// discardView is used by strategies asked to discard
+func (g *Game) discardView(p *Player) PlayerView {
+	return g.view(p, Decision{Type: DecisionDiscard, Seat: g.Seat(p)})
+}
+
+// 合法手から捨てられるカードを取り出す
+func discardCards(actions []Action) []int {
+	var cards []int
+	for _, a := range actions {
+		if a.Type() == DecisionDiscard {
+			cards = append(cards, a.Card())
+		}
+	}
+	return uniqueCards(cards)
+}
+
+// 合法手からcardを捨てたときの対象を取り出す
+func discardTargets(actions []Action, card int) []int {
+	var targets []int
+	seen := map[int]bool{}
+	for _, a := range actions {
+		if a.Type() == DecisionDiscard && a.Card() == card && a.Target() >= 0 && !seen[a.Target()] {
+			seen[a.Target()] = true
+			targets = append(targets, a.Target())
+		}
+	}
+	return targets
+}
+
+// 合法手から捜査の予想を取り出す
+func investigationExpects(actions []Action, target int) []int {
+	var expects []int
+	for _, a := range actions {
+		if a.Type() == DecisionDiscard && a.Card() == 2 && a.Target() == target {
+			expects = append(expects, a.Expect())
+		}
+	}
+	return expects
+}
@@ -0,0 +1,260 @@
+// Package xenotest はゲームの規則のテストを短く書くための道具。
+// Builder で局面を作り、Script で判断を順に打たせ、Recorder で起きた出来事を確かめる。
+//
+//	g := xenotest.NewGame(2).Hand(0, 6, 9).Hand(1, 5).Deck(3, 1).Build(t)
+//	rec := xenotest.Record(g)
+//	xenotest.Play(t, g, "d6>P2")
+//	rec.Expect(t, xeno.Event{Type: xeno.EventConfrontation, Seat: 0, Target: 1, Winner: 0})
+package xenotest
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/u-one/go-xeno/xeno"
+)
+
+// Builder は局面を組み立てる。席は0から数え、書かなかったカードは残りから Seed の乱数で配る
+type Builder struct {
+	n          int
+	names      []string
+	hands      [][]int
+	discarded  [][]int
+	protected  []bool
+	wise       []bool
+	dropped    []bool
+	known      []map[int]int
+	deck       []int
+	deckSet    bool
+	reinc      int
+	reincSet   bool
+	boy        bool
+	turn       int
+	seed       int64
+	strategies []xeno.PlayerStrategy
+	shuffles   [][]int
+	out        io.Writer
+}
+
+// NewGame returns a builder of a game with n players
+func NewGame(n int) *Builder {
+	return &Builder{
+		n:          n,
+		hands:      make([][]int, n),
+		discarded:  make([][]int, n),
+		protected:  make([]bool, n),
+		wise:       make([]bool, n),
+		dropped:    make([]bool, n),
+		known:      make([]map[int]int, n),
+		strategies: make([]xeno.PlayerStrategy, n),
+	}
+}
+
+func (b *Builder) Names(names ...string) *Builder {
+	b.names = names
+	return b
+}
+
+// Hand sets the hand of seat. 0 is an unknown card.
+// The player to move starts from the discard with 2 cards, or draws with 1 card.
+func (b *Builder) Hand(seat int, cards ...int) *Builder {
+	b.hands[seat] = cards
+	return b
+}
+
+func (b *Builder) Discarded(seat int, cards ...int) *Builder {
+	b.discarded[seat] = cards
+	return b
+}
+
+func (b *Builder) Protected(seat int) *Builder {
+	b.protected[seat] = true
+	return b
+}
+
+func (b *Builder) CalledWise(seat int) *Builder {
+	b.wise[seat] = true
+	return b
+}
+
+func (b *Builder) Dropped(seat int) *Builder {
+	b.dropped[seat] = true
+	return b
+}
+
+// Knows makes seat know that target holds card
+func (b *Builder) Knows(seat, target, card int) *Builder {
+	if b.known[seat] == nil {
+		b.known[seat] = map[int]int{}
+	}
+	b.known[seat][target] = card
+	return b
+}
+
+// Deck sets the deck, top first. 0 is an unknown card.
+// Cards used nowhere are added to the discarded cards so that all 18 cards are in the game.
+func (b *Builder) Deck(cards ...int) *Builder {
+	b.deck, b.deckSet = cards, true
+	return b
+}
+
+// Reincarnation sets the reincarnation card. 0 means it has been used.
+func (b *Builder) Reincarnation(card int) *Builder {
+	b.reinc, b.reincSet = card, true
+	return b
+}
+
+func (b *Builder) BoyAppeared() *Builder {
+	b.boy = true
+	return b
+}
+
+// Turn sets the turn. The player to move is turn % players.
+func (b *Builder) Turn(turn int) *Builder {
+	b.turn = turn
+	return b
+}
+
+// Seed sets the seed dealing unknown cards and driving the default random strategies
+func (b *Builder) Seed(seed int64) *Builder {
+	b.seed = seed
+	return b
+}
+
+// Strategy sets the strategy of seat. Others play at random.
+func (b *Builder) Strategy(seat int, s xeno.PlayerStrategy) *Builder {
+	b.strategies[seat] = s
+	return b
+}
+
+// Shuffles sets the order of the deck after each wise, top first
+func (b *Builder) Shuffles(orders ...[]int) *Builder {
+	b.shuffles = orders
+	return b
+}
+
+// Output sets where the game writes. The output is discarded by default.
+func (b *Builder) Output(w io.Writer) *Builder {
+	b.out = w
+	return b
+}
+
+// Position returns the position in the notation of xeno.ParsePosition
+func (b *Builder) Position() string {
+	var s strings.Builder
+	if b.names != nil {
+		fmt.Fprintf(&s, "players: %s\n", strings.Join(b.names, ", "))
+	}
+	fmt.Fprintf(&s, "turn: %d\n", b.turn)
+	if b.boy {
+		fmt.Fprintln(&s, "boy: yes")
+	}
+	if b.deckSet {
+		fmt.Fprintf(&s, "deck: %s\n", cards(b.deck))
+	}
+	if b.reincSet {
+		if b.reinc == 0 {
+			fmt.Fprintln(&s, "reincarnation: -")
+		} else {
+			fmt.Fprintf(&s, "reincarnation: %d\n", b.reinc)
+		}
+	}
+	for i := 0; i < b.n; i++ {
+		var items []string
+		switch {
+		case b.hands[i] != nil:
+			items = append(items, "hand "+cards(b.hands[i]))
+		case !b.dropped[i]:
+			items = append(items, "hand ?")
+		}
+		if len(b.discarded[i]) > 0 {
+			items = append(items, "discarded "+cards(b.discarded[i]))
+		}
+		if b.protected[i] {
+			items = append(items, "protected")
+		}
+		if b.wise[i] {
+			items = append(items, "wise")
+		}
+		if b.dropped[i] {
+			items = append(items, "dropped")
+		}
+		if len(b.known[i]) > 0 {
+			var known []string
+			for t := 0; t < b.n; t++ {
+				if c, ok := b.known[i][t]; ok {
+					known = append(known, fmt.Sprintf("P%d=%d", t+1, c))
+				}
+			}
+			items = append(items, "known "+strings.Join(known, " "))
+		}
+		fmt.Fprintf(&s, "P%d: %s\n", i+1, strings.Join(items, "; "))
+	}
+	return s.String()
+}
+
+// Build makes the game and fails t if the position is invalid.
+// The game waits for the first decision, like after Game.Start.
+func (b *Builder) Build(t testing.TB) *xeno.Game {
+	t.Helper()
+	g, err := xeno.ParsePosition(b.Position(), rand.New(rand.NewSource(b.seed)))
+	if err != nil {
+		t.Fatalf("xenotest: %v\n%s", err, b.Position())
+	}
+	for i, s := range b.strategies {
+		if s != nil {
+			g.Players[i].SetStrategy(s)
+		}
+	}
+	if b.shuffles != nil {
+		g.Deck.SetShuffler(&shuffler{t: t, orders: b.shuffles})
+	}
+	if b.out != nil {
+		g.SetOutput(b.out)
+	}
+	return g
+}
+
+func cards(cs []int) string {
+	s := make([]string, len(cs))
+	for i, c := range cs {
+		if c == 0 {
+			s[i] = "?"
+		} else {
+			s[i] = fmt.Sprint(c)
+		}
+	}
+	return strings.Join(s, " ")
+}
+
+// 決められた順に並べるシャッフラー
+type shuffler struct {
+	t      testing.TB
+	orders [][]int
+}
+
+func (s *shuffler) Shuffle(cs []int) []int {
+	if len(s.orders) == 0 {
+		s.t.Errorf("xenotest: no more shuffle for %v", cs)
+		return cs
+	}
+	order := s.orders[0]
+	s.orders = s.orders[1:]
+	count := map[int]int{}
+	for _, c := range cs {
+		count[c]++
+	}
+	for _, c := range order {
+		count[c]--
+	}
+	for c, n := range count {
+		if n != 0 {
+			s.t.Errorf("xenotest: shuffle %v is not an order of %v ([%d])", order, cs, c)
+			return cs
+		}
+	}
+	return append([]int{}, order...)
+}
@@ -0,0 +1,85 @@
+package xenotest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/u-one/go-xeno/xeno"
+)
+
+func TestBuilder_Position(t *testing.T) {
+	b := NewGame(3).Names("A", "B", "C").Turn(4).BoyAppeared().Deck(3, 0).Reincarnation(0).
+		Hand(1, 6, 9).Discarded(0, 1, 2).Protected(0).CalledWise(1).Knows(1, 0, 5).Dropped(2)
+	want := `players: A, B, C
+turn: 4
+boy: yes
+deck: 3 ?
+reincarnation: -
+P1: hand ?; discarded 1 2; protected
+P2: hand 6 9; wise; known P1=5
+P3: dropped
+`
+	if got := b.Position(); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+	g := b.Build(t)
+	if d := g.Pending(); d.Type != xeno.DecisionDiscard || d.Seat != 1 {
+		t.Errorf("pending: %v", d)
+	}
+	if !g.Players[0].Protected() || !g.Players[2].Dropped() {
+		t.Error("flags are not set")
+	}
+}
+
+func TestBuilder_Confrontation(t *testing.T) {
+	g := NewGame(2).Hand(0, 6, 9).Hand(1, 5).Deck(3, 1).Build(t)
+	rec := Record(g)
+	Play(t, g, "d6>P2")
+	rec.Expect(t,
+		xeno.Event{Type: xeno.EventDiscard, Seat: 0, Card: 6},
+		xeno.Event{Type: xeno.EventConfrontation, Seat: 0, Target: 1, Winner: 0},
+		xeno.Event{Type: xeno.EventDropout, Seat: 1, Card: 6},
+	)
+	if !g.Over() || len(g.Winners()) != 1 || g.Winners()[0] != g.Players[0] {
+		t.Errorf("winners: %v", g.Winners())
+	}
+}
+
+func TestBuilder_Guard(t *testing.T) {
+	g := NewGame(2).Hand(0, 5, 8).Hand(1, 3).Protected(1).Deck(2, 1).Build(t)
+	rec := Record(g)
+	Play(t, g, "d5>P2")
+	rec.Expect(t, xeno.Event{Type: xeno.EventGuarded, Seat: 0, Target: 1})
+	if rec.Has(xeno.Event{Type: xeno.EventForcedDiscard, Seat: 1, Target: 0, Card: 3}) {
+		t.Error("protected player discarded")
+	}
+}
+
+func TestBuilder_Invalid(t *testing.T) {
+	ft := &fakeT{TB: t}
+	func() {
+		defer func() { recover() }()
+		NewGame(2).Hand(0, 10, 10).Build(ft)
+	}()
+	if !strings.Contains(ft.fatal, "too many [10]") {
+		t.Errorf("got: %q", ft.fatal)
+	}
+}
+
+// 失敗を記録するだけの testing.TB
+type fakeT struct {
+	testing.TB
+	fatal string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Fatalf(format string, args ...interface{}) {
+	t.fatal = fmt.Sprintf(format, args...)
+	panic(t.fatal)
+}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.fatal = fmt.Sprintf(format, args...)
+}
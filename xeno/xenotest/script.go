@@ -0,0 +1,168 @@
+package xenotest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/u-one/go-xeno/xeno"
+)
+
+// Script は書いた順に判断を返す戦略。手は xeno.FormatMove の記法で書き、席は省略できる。
+// 判断の種類や席が違う手、反則の手、手が足りないときは t を失敗させる。
+//
+//	xenotest.NewScript(t, "d7", "wise→6", "d6>P2")
+type Script struct {
+	t     testing.TB
+	moves []string
+	next  int
+	game  *xeno.Game // 直近の判断のゲーム。公開処刑・疫病の判断で使う
+}
+
+// NewScript returns a strategy playing moves in order
+func NewScript(t testing.TB, moves ...string) *Script {
+	return &Script{t: t, moves: moves}
+}
+
+// Done reports whether all moves have been played
+func (s *Script) Done() bool {
+	return s.next == len(s.moves)
+}
+
+// 次の手を取り出して判断と照らし合わせる
+func (s *Script) take(typ xeno.DecisionType, seat int) xeno.Action {
+	if s.Done() {
+		s.t.Fatalf("xenotest: script has no move for %v of P%d", typ, seat+1)
+	}
+	m := s.moves[s.next]
+	s.next++
+	d, a, err := parseMove(m, seat)
+	if err != nil {
+		s.t.Fatalf("xenotest: %v", err)
+	}
+	if d.Seat != seat || d.Type != typ {
+		s.t.Fatalf("xenotest: %q is played for %v of P%d", m, typ, seat+1)
+	}
+	return a
+}
+
+func (s *Script) seat(p *xeno.Player) int {
+	if s.game == nil {
+		s.t.Fatalf("xenotest: script is asked before its first discard")
+	}
+	return s.game.Seat(p)
+}
+
+func (s *Script) SelectDiscard(g *xeno.Game, p *xeno.Player) xeno.CardEvent {
+	s.game = g
+	a := s.take(xeno.DecisionDiscard, g.Seat(p))
+	if !xeno.IsLegal(g.View(p), a) {
+		s.t.Fatalf("xenotest: illegal move %v with %v", a, p.Hand())
+	}
+	e := xeno.CardEvent{Card: a.Card(), Expect: a.Expect()}
+	if a.Target() >= 0 {
+		e.Target = g.Players[a.Target()]
+	}
+	return e
+}
+
+func (s *Script) SelectFromWise(g *xeno.Game, candidates []int) int {
+	s.game = g
+	a := s.take(xeno.DecisionWise, g.Pending().Seat)
+	if !xeno.IsLegal(g.View(g.Players[g.Pending().Seat]), a) {
+		s.t.Fatalf("xenotest: illegal move %v from %v", a, candidates)
+	}
+	return a.Card()
+}
+
+func (s *Script) SelectOnPublicExecution(p, target *xeno.Player, hand xeno.Hand) int {
+	a := s.take(xeno.DecisionPublicExecution, s.seat(p))
+	if !hand.Has(a.Card()) {
+		s.t.Fatalf("xenotest: illegal move %v from %v", a, hand)
+	}
+	return a.Card()
+}
+
+func (s *Script) SelectOnPlague(p, target *xeno.Player, hand xeno.Hand) int {
+	a := s.take(xeno.DecisionPlague, s.seat(p))
+	if a.Index() >= hand.Count() {
+		s.t.Fatalf("xenotest: illegal move %v from %v", a, hand)
+	}
+	return hand.At(a.Index())
+}
+
+func (s *Script) KnowByClairvoyance(g *xeno.Game, p, target *xeno.Player, c int) {}
+
+func (s *Script) OnOpponentEvent(g *xeno.Game, p, opponent *xeno.Player, e xeno.CardEvent) {}
+
+// 席を省略した手はseatの手とする
+func parseMove(m string, seat int) (xeno.Decision, xeno.Action, error) {
+	if !strings.HasPrefix(m, "P") {
+		m = fmt.Sprintf("P%d %s", seat+1, m)
+	}
+	return xeno.ParseMove(m)
+}
+
+// Play steps moves of the pending decisions in order, and fails t if one is wrong.
+// The seat of a move can be omitted.
+func Play(t testing.TB, g *xeno.Game, moves ...string) {
+	t.Helper()
+	for _, m := range moves {
+		if !strings.HasPrefix(m, "P") {
+			m = fmt.Sprintf("P%d %s", g.Pending().Seat+1, m)
+		}
+		if err := g.Play(m); err != nil {
+			t.Fatalf("xenotest: %q: %v", m, err)
+		}
+	}
+}
+
+// Recorder は公開された出来事を記録する
+type Recorder struct {
+	Events []xeno.Event
+}
+
+// Record returns a recorder observing g from now
+func Record(g *xeno.Game) *Recorder {
+	r := &Recorder{}
+	g.AddObserver(r)
+	return r
+}
+
+func (r *Recorder) OnEvent(g *xeno.Game, e xeno.Event) {
+	r.Events = append(r.Events, e)
+}
+
+// Reset forgets the recorded events
+func (r *Recorder) Reset() {
+	r.Events = nil
+}
+
+// Expect fails t unless want occurred in this order, possibly with other events between them.
+// Events are compared by String, so fields not shown there (like Turn) are ignored.
+func (r *Recorder) Expect(t testing.TB, want ...xeno.Event) {
+	t.Helper()
+	i := 0
+	for _, e := range r.Events {
+		if i < len(want) && e.String() == want[i].String() {
+			i++
+		}
+	}
+	if i < len(want) {
+		var got []string
+		for _, e := range r.Events {
+			got = append(got, e.String())
+		}
+		t.Errorf("xenotest: event %q did not occur in:\n%s", want[i].String(), strings.Join(got, "\n"))
+	}
+}
+
+// Has reports whether an event equal to e by String occurred
+func (r *Recorder) Has(e xeno.Event) bool {
+	for _, got := range r.Events {
+		if got.String() == e.String() {
+			return true
+		}
+	}
+	return false
+}
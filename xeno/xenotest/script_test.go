@@ -0,0 +1,76 @@
+package xenotest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/u-one/go-xeno/xeno"
+)
+
+func TestScript(t *testing.T) {
+	p1 := NewScript(t, "d7", "wise[8,5,6]→6", "d6>P2")
+	p2 := NewScript(t, "P2 d4")
+	g := NewGame(2).Hand(0, 7, 3).Hand(1, 4).Deck(2, 8, 5, 6, 1, 9).
+		Strategy(0, p1).Strategy(1, p2).Shuffles([]int{9, 1, 5, 8}).Build(t)
+	rec := Record(g)
+	g.Resume()
+
+	if !p1.Done() || !p2.Done() {
+		t.Error("script is not done")
+	}
+	rec.Expect(t,
+		xeno.Event{Type: xeno.EventDiscard, Seat: 0, Card: 7},
+		xeno.Event{Type: xeno.EventDiscard, Seat: 1, Card: 4},
+		xeno.Event{Type: xeno.EventWise, Seat: 0},
+		xeno.Event{Type: xeno.EventConfrontation, Seat: 0, Target: 1, Winner: 0},
+	)
+	if len(g.Winners()) != 1 || g.Winners()[0] != g.Players[0] {
+		t.Errorf("winners: %v", g.Winners())
+	}
+	pos, err := xeno.FormatPosition(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(pos, "deck: 9 1 5 8\n") {
+		t.Errorf("deck is not shuffled as written:\n%s", pos)
+	}
+}
+
+func TestScript_Mismatch(t *testing.T) {
+	tests := []struct {
+		moves []string
+		want  string
+	}{
+		{nil, "no move"},
+		{[]string{"wise→3"}, "is played for"},
+		{[]string{"P2 d6"}, "is played for"},
+		{[]string{"d10"}, "illegal move"},
+		{[]string{"x"}, "invalid move"},
+	}
+	for _, tt := range tests {
+		ft := &fakeT{TB: t}
+		g := NewGame(2).Hand(0, 6, 10).Hand(1, 5).Strategy(0, NewScript(ft, tt.moves...)).Build(t)
+		func() {
+			defer func() { recover() }()
+			g.Resume()
+		}()
+		if !strings.Contains(ft.fatal, tt.want) {
+			t.Errorf("%v: want: %q, got: %q", tt.moves, tt.want, ft.fatal)
+		}
+	}
+}
+
+func TestScript_PlagueIndex(t *testing.T) {
+	ft := &fakeT{TB: t}
+	s := NewScript(ft, "d5>P2", "plague>P2→1")
+	g := NewGame(2).Hand(0, 5, 3).Hand(1, 4).Deck(2, 8, 6, 1, 9).Strategy(0, s).Build(t)
+	s.SelectDiscard(g, g.Players[0])
+	// 1枚しかない手札の右は選べない
+	func() {
+		defer func() { recover() }()
+		s.SelectOnPlague(g.Players[0], g.Players[1], g.Players[1].Hand())
+	}()
+	if !strings.Contains(ft.fatal, "illegal move") {
+		t.Errorf("want: illegal move, got: %q", ft.fatal)
+	}
+}